@@ -0,0 +1,260 @@
+package comgo
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+/*
+ * Archive - COMTRADE records of a zip, tar or tar.gz archive, read without extracting to disk
+ * @Records: Files of each record of the archive, grouped by GroupFiles
+ * @zipFiles: Files of zip archive, decompressed when opened
+ * @tarFiles: Content of the files of tar archive
+ * @closer: File of the archive opened by OpenArchive
+ */
+type Archive struct {
+	Records  []RecordFiles
+	zipFiles map[string]*zip.File
+	tarFiles map[string][]byte
+	closer   io.Closer
+}
+
+func (m *Archive) GetRecords() []RecordFiles {
+	if m != nil {
+		return m.Records
+	}
+	return nil
+}
+
+// Opens the archive file at path, zip, tar or tar.gz detected from its content
+// zip files are decompressed when a record is read, tar files are read at once
+// The archive must be released with Close
+func OpenArchive(name string) (*Archive, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	magic := make([]byte, 4)
+	if _, err := file.ReadAt(magic, 0); err != nil && err != io.EOF {
+		file.Close()
+		return nil, err
+	}
+
+	var archive *Archive
+	if isZip(magic) {
+		archive, err = readZip(file, info.Size())
+	} else {
+		archive, err = readTar(bufio.NewReader(file))
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if archive.zipFiles != nil {
+		// zip files are read from the archive file until Close
+		archive.closer = file
+	} else {
+		file.Close()
+	}
+	return archive, nil
+}
+
+// Reads the archive rd, zip, tar or tar.gz detected from its content
+func ReadArchive(rd io.Reader) (*Archive, error) {
+	br := bufio.NewReader(rd)
+	magic, _ := br.Peek(4)
+	if isZip(magic) {
+		content, err := ioutil.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		return readZip(bytes.NewReader(content), int64(len(content)))
+	}
+	return readTar(br)
+}
+
+// Releases the archive file opened by OpenArchive
+func (m *Archive) Close() error {
+	if m == nil || m.closer == nil {
+		return nil
+	}
+	err := m.closer.Close()
+	m.closer = nil
+	return err
+}
+
+// Check for the signature of a zip archive
+func isZip(magic []byte) bool {
+	return bytes.HasPrefix(magic, []byte("PK\x03\x04")) || bytes.HasPrefix(magic, []byte("PK\x05\x06"))
+}
+
+func readZip(ra io.ReaderAt, size int64) (*Archive, error) {
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, err
+	}
+	archive := Archive{zipFiles: make(map[string]*zip.File)}
+	var names []string
+	for _, v := range zr.File {
+		if v.FileInfo().IsDir() {
+			continue
+		}
+		archive.zipFiles[v.Name] = v
+		names = append(names, v.Name)
+	}
+	archive.Records = GroupFiles(names)
+	return &archive, nil
+}
+
+// Reads every regular file of tar or tar.gz archive
+func readTar(br *bufio.Reader) (*Archive, error) {
+	var rd io.Reader = br
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1F, 0x8B}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		rd = gz
+	}
+
+	archive := Archive{tarFiles: make(map[string][]byte)}
+	var names []string
+	tr := tar.NewReader(rd)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		// Only the files of records are kept in memory
+		if _, ext := splitRecordName(header.Name); ext == "" {
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		archive.tarFiles[header.Name] = content
+		names = append(names, header.Name)
+	}
+	archive.Records = GroupFiles(names)
+	return &archive, nil
+}
+
+// Opens the file name of the archive, to be used with ReadRecord
+func (m *Archive) Open(name string) (io.ReadCloser, error) {
+	if m != nil {
+		if file, ok := m.zipFiles[name]; ok {
+			return file.Open()
+		}
+		if content, ok := m.tarFiles[name]; ok {
+			return ioutil.NopCloser(bytes.NewReader(content)), nil
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Return the files of the record name, its path in the archive without extension
+// or the base name only, compared case-insensitively
+func (m *Archive) Find(name string) (*RecordFiles, error) {
+	records := m.GetRecords()
+	for k := range records {
+		if strings.EqualFold(records[k].Name, name) {
+			return &records[k], nil
+		}
+	}
+	for k := range records {
+		if strings.EqualFold(path.Base(records[k].Name), name) {
+			return &records[k], nil
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Loads the record name of the archive, see Find
+func (m *Archive) Load(name string) (*CFG, error) {
+	files, err := m.Find(name)
+	if err != nil {
+		return nil, err
+	}
+	cfg := New()
+	if err := cfg.ReadRecord(files, m.Open); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Return the trigger time of each record of the archive, read from its .cfg or .cff file
+func (m *Archive) TriggerTimes() ([]time.Time, error) {
+	records := m.GetRecords()
+	times := make([]time.Time, len(records))
+	for k, v := range records {
+		cfg := New()
+		read, name := cfg.ReadCFG, v.CFG
+		if name == "" {
+			read, name = cfg.ReadCFF, v.CFF
+		}
+		file, err := m.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		err = read(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		times[k] = cfg.GetTriggerTime()
+	}
+	return times, nil
+}
+
+// Loads the record of the archive whose trigger time is the closest to t
+func (m *Archive) LoadTrigger(t time.Time) (*CFG, error) {
+	times, err := m.TriggerTimes()
+	if err != nil {
+		return nil, err
+	}
+	if len(times) == 0 {
+		return nil, errors.New("no record in archive")
+	}
+	best := 0
+	for k, v := range times {
+		if absDuration(v.Sub(t)) < absDuration(times[best].Sub(t)) {
+			best = k
+		}
+	}
+	cfg := New()
+	if err := cfg.ReadRecord(&m.Records[best], m.Open); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,156 @@
+package comgo
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+/*
+ * testFile - File of the test archives
+ * @name: Path of the file in the archive
+ * @content: File content
+ */
+type testFile struct {
+	name    string
+	content []byte
+}
+
+// Files of the test archives, mixed-case names of test1 and test2 of examples/data
+func testArchiveFiles(t *testing.T) []testFile {
+	t.Helper()
+	cfg1, err := os.ReadFile("examples/data/test1.cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg2, err := os.ReadFile("examples/data/test2.cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []testFile{
+		{"Records/Fault1.CFG", cfg1},
+		{"Records/Fault1.Dat", readTestDAT(t, "test1")},
+		{"Records/fault2.cfg", cfg2},
+		{"Records/FAULT2.DAT", readTestDAT(t, "test2")},
+		{"Records/readme.txt", []byte("not a record")},
+		{"Other/Fault1.hdr", []byte("header without configuration")},
+		{"Other/Notes/RECORD1", []byte("no extension")},
+	}
+}
+
+// Writes files to a zip archive, with a directory entry
+func zipArchive(t *testing.T, files []testFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.Create("Records/"); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range files {
+		w, err := zw.Create(v.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(v.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Writes files to a tar archive, compressed by gzip if compress is set
+func tarArchive(t *testing.T, files []testFile, compress bool) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var gz *gzip.Writer
+	var w io.Writer = &buf
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(&tar.Header{Name: "Records/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: v.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(v.content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(v.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveFind(t *testing.T) {
+	files := testArchiveFiles(t)
+	archives := []struct {
+		name    string
+		content []byte
+	}{
+		{"zip", zipArchive(t, files)},
+		{"tar", tarArchive(t, files, false)},
+		{"tar.gz", tarArchive(t, files, true)},
+	}
+	tests := []struct {
+		name    string
+		record  string
+		samples int
+	}{
+		{"records/fault1", "Records/Fault1", 11001},
+		{"FAULT1", "Records/Fault1", 11001},
+		{"Records/FAULT2", "Records/fault2", 13248},
+		{"fault2", "Records/fault2", 13248},
+		{"Other/Fault1", "", 0},
+		{"readme", "", 0},
+		{"record1", "", 0},
+	}
+	for _, a := range archives {
+		archive, err := ReadArchive(bytes.NewReader(a.content))
+		if err != nil {
+			t.Fatalf("%s: %v", a.name, err)
+		}
+		for _, tt := range tests {
+			files, err := archive.Find(tt.name)
+			if tt.record == "" {
+				if !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("%s %q: got %v, want fs.ErrNotExist", a.name, tt.name, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s %q: %v", a.name, tt.name, err)
+				continue
+			}
+			if files.GetName() != tt.record {
+				t.Errorf("%s %q: found %s, want %s", a.name, tt.name, files.GetName(), tt.record)
+			}
+			cfg, err := archive.Load(tt.name)
+			if err != nil {
+				t.Errorf("%s %q: %v", a.name, tt.name, err)
+				continue
+			}
+			if cfg.GetSamplingNumber() != tt.samples || len(cfg.GetDataFileContent()) == 0 {
+				t.Errorf("%s %q: %d samples, want %d", a.name, tt.name, cfg.GetSamplingNumber(), tt.samples)
+			}
+		}
+	}
+}
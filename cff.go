@@ -0,0 +1,219 @@
+package comgo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Prefix of the section separator lines of .cff file
+// --- file type: CFG ---
+// --- file type: DAT BINARY: 1234 ---
+const cffSectionPrefix = "--- file type:"
+
+/*
+ * cffSection - One section of a combined .cff file
+ * @kind: CFG, INF, HDR or DAT
+ * @format: Data file type of DAT section
+ * @content: Section content
+ */
+type cffSection struct {
+	kind    string
+	format  string
+	content []byte
+}
+
+// Reads the Comtrade combined file (.cff) of C37.111-2013
+// The CFG, INF, HDR and DAT sections populate the same fields as
+// ReadCFG and ReadDAT
+func (cfg *CFG) ReadCFF(rd io.Reader) (err error) {
+	return cfg.ReadCFFContext(context.Background(), rd)
+}
+
+// Reads the Comtrade combined file (.cff) like ReadCFF
+// Reading and decoding stop when ctx is done, returning a CancelError
+func (cfg *CFG) ReadCFFContext(ctx context.Context, rd io.Reader) (err error) {
+	content, err := ioutil.ReadAll(&contextReader{ctx, rd})
+	if err != nil {
+		if ctx.Err() != nil {
+			return &CancelError{Samples: 0, Err: ctx.Err()}
+		}
+		return err
+	}
+
+	sections, err := splitCFF(content)
+	if err != nil {
+		return err
+	}
+
+	var dat *cffSection
+	for k := range sections {
+		section := &sections[k]
+		switch section.kind {
+		case "CFG":
+			if err := cfg.ReadCFG(bytes.NewReader(section.content)); err != nil {
+				return err
+			}
+		case "INF":
+			cfg.InfoFileContent = section.content
+		case "HDR":
+			cfg.HeaderFileContent = section.content
+		case "DAT":
+			dat = section
+		}
+	}
+
+	if cfg.GetAnalogDetail() == nil {
+		return cffError("no CFG section", "")
+	}
+	if dat == nil {
+		return nil
+	}
+	if strings.TrimSpace(cfg.GetDataFileType()) == "" {
+		cfg.DataFileType = dat.format
+	}
+	return cfg.ReadDATContext(ctx, bytes.NewReader(dat.content))
+}
+
+// Splits .cff file content into its sections
+func splitCFF(content []byte) (sections []cffSection, err error) {
+	for {
+		// Skip blank lines before the section separator
+		content = bytes.TrimLeft(content, "\r\n\t ")
+		if len(content) == 0 {
+			return sections, nil
+		}
+
+		end := bytes.IndexByte(content, '\n')
+		if end < 0 {
+			end = len(content)
+		}
+		section, count, err := parseCFFSeparator(content[:end])
+		if err != nil {
+			return nil, err
+		}
+		if end < len(content) {
+			end++
+		}
+		content = content[end:]
+
+		if count >= 0 {
+			// Binary data section with byte count
+			if count > len(content) {
+				return nil, &ParseError{File: "CFF", Name: "DAT section", Text: strconv.Itoa(count), Err: ErrTruncated}
+			}
+			section.content, content = content[:count], content[count:]
+		} else {
+			// Text section ends at the next separator
+			next := len(content)
+			for pos := 0; pos < len(content); {
+				if isCFFSeparator(content[pos:]) {
+					next = pos
+					break
+				}
+				lineEnd := bytes.IndexByte(content[pos:], '\n')
+				if lineEnd < 0 {
+					break
+				}
+				pos += lineEnd + 1
+			}
+			section.content, content = content[:next], content[next:]
+		}
+		sections = append(sections, section)
+	}
+}
+
+// Check if b starts with a section separator line
+func isCFFSeparator(b []byte) bool {
+	return len(b) >= len(cffSectionPrefix) && strings.EqualFold(string(b[:len(cffSectionPrefix)]), cffSectionPrefix)
+}
+
+// Parses a section separator line
+// return byte count of binary DAT section, -1 for text sections
+func parseCFFSeparator(line []byte) (section cffSection, count int, err error) {
+	text := ByteToString(line)
+	if !isCFFSeparator([]byte(text)) || !strings.HasSuffix(text, "---") {
+		return section, -1, cffError("invalid section separator", text)
+	}
+	text = strings.TrimSpace(strings.TrimSuffix(text[len(cffSectionPrefix):], "---"))
+
+	tempList := strings.Split(text, ":")
+	fields := strings.Fields(strings.ToUpper(tempList[0]))
+	if len(fields) == 0 {
+		return section, -1, cffError("missing section type", "")
+	}
+	section.kind = fields[0]
+	switch section.kind {
+	case "CFG", "INF", "HDR":
+		return section, -1, nil
+	case "DAT":
+	default:
+		return section, -1, cffError("unknown section type", section.kind)
+	}
+
+	if len(fields) < 2 {
+		return section, -1, cffError("missing DAT file type", "")
+	}
+	section.format = normalizeDataFileType(fields[1])
+	if section.format == DataFileASCII {
+		return section, -1, nil
+	}
+	if len(tempList) < 2 {
+		return section, -1, cffError("missing DAT byte count", "")
+	}
+	if count, err = strconv.Atoi(strings.TrimSpace(tempList[1])); err != nil || count < 0 {
+		return section, -1, cffError("invalid DAT byte count", tempList[1])
+	}
+	return section, count, nil
+}
+
+// Return a ParseError of the .cff file
+func cffError(name, text string) error {
+	return &ParseError{File: "CFF", Name: name, Text: text, Err: ErrFormat}
+}
+
+// Writes the record as a combined file (.cff) of C37.111-2013
+// the data section is written in format with the conversion factors of Rescale
+// cfg is not modified
+func (cfg *CFG) WriteCFF(w io.Writer, format string) error {
+	out, err := cfg.Rescale(format)
+	if err != nil {
+		return err
+	}
+	var cfgBuf, datBuf bytes.Buffer
+	if err := out.WriteCFG(&cfgBuf); err != nil {
+		return err
+	}
+	if err := out.WriteDAT(&datBuf, format); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeText := func(kind string, content []byte) {
+		fmt.Fprintf(&buf, "%s %s ---\r\n", cffSectionPrefix, kind)
+		buf.Write(content)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			buf.WriteString("\r\n")
+		}
+	}
+	writeText("CFG", cfgBuf.Bytes())
+	if len(cfg.GetInfoFileContent()) > 0 {
+		writeText("INF", cfg.GetInfoFileContent())
+	}
+	if len(cfg.GetHeaderFileContent()) > 0 {
+		writeText("HDR", cfg.GetHeaderFileContent())
+	}
+	if out.dataFileType() == DataFileASCII {
+		writeText("DAT ASCII", datBuf.Bytes())
+	} else {
+		fmt.Fprintf(&buf, "%s DAT %s: %d ---\r\n", cffSectionPrefix, out.dataFileType(), datBuf.Len())
+		buf.Write(datBuf.Bytes())
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
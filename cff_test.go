@@ -0,0 +1,130 @@
+package comgo
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestParseCFFSeparator(t *testing.T) {
+	tests := []struct {
+		line   string
+		kind   string
+		format string
+		count  int
+		ok     bool
+	}{
+		{"--- file type: CFG ---", "CFG", "", -1, true},
+		{"--- File Type: hdr ---\r", "HDR", "", -1, true},
+		{"--- file type: INF ---", "INF", "", -1, true},
+		{"--- file type: DAT ASCII ---", "DAT", DataFileASCII, -1, true},
+		{"--- file type: DAT BINARY: 1234 ---", "DAT", DataFileBinary, 1234, true},
+		{"--- file type: DAT float32 : 16 ---", "DAT", DataFileFloat32, 16, true},
+		{"--- file type: DAT BINARY32: 0 ---", "DAT", DataFileBinary32, 0, true},
+		{"file type: CFG", "", "", -1, false},
+		{"--- file type: CFG", "", "", -1, false},
+		{"--- file type: ---", "", "", -1, false},
+		{"--- file type: XYZ ---", "", "", -1, false},
+		{"--- file type: DAT ---", "", "", -1, false},
+		{"--- file type: DAT BINARY ---", "", "", -1, false},
+		{"--- file type: DAT BINARY: -1 ---", "", "", -1, false},
+		{"--- file type: DAT BINARY: 12a ---", "", "", -1, false},
+	}
+	for _, tt := range tests {
+		section, count, err := parseCFFSeparator([]byte(tt.line))
+		if !tt.ok {
+			if !errors.Is(err, ErrFormat) {
+				t.Errorf("%q: got %v, want ErrFormat", tt.line, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.line, err)
+			continue
+		}
+		if section.kind != tt.kind || section.format != tt.format || count != tt.count {
+			t.Errorf("%q: got %s %s %d, want %s %s %d", tt.line, section.kind, section.format, count, tt.kind, tt.format, tt.count)
+		}
+	}
+}
+
+func TestSplitCFF(t *testing.T) {
+	// Binary data holding a separator line, only the byte count ends the section
+	dat := []byte("\x01\x00\n--- file type: HDR ---\n\x00\xff")
+	tests := []struct {
+		name    string
+		content string
+		kinds   []string
+		texts   []string
+		err     error
+	}{
+		{"text sections", "--- file type: CFG ---\r\na,b\r\n--- file type: HDR ---\r\nheader\r\n",
+			[]string{"CFG", "HDR"}, []string{"a,b\r\n", "header\r\n"}, nil},
+		{"blank lines between sections", "\r\n--- file type: CFG ---\na\n\n--- file type: INF ---\n[x]",
+			[]string{"CFG", "INF"}, []string{"a\n\n", "[x]"}, nil},
+		{"binary DAT byte count", "--- file type: CFG ---\na\n" +
+			"--- file type: DAT BINARY: " + strconv.Itoa(len(dat)) + " ---\n" + string(dat) + "--- file type: HDR ---\nh",
+			[]string{"CFG", "DAT", "HDR"}, []string{"a\n", string(dat), "h"}, nil},
+		{"ASCII DAT", "--- file type: DAT ASCII ---\n1,0,5\n2,1,6\n",
+			[]string{"DAT"}, []string{"1,0,5\n2,1,6\n"}, nil},
+		{"truncated binary DAT", "--- file type: DAT BINARY: " + strconv.Itoa(len(dat)+1) + " ---\n" + string(dat), nil, nil, ErrTruncated},
+		{"missing separator", "a,b\n--- file type: CFG ---\n", nil, nil, ErrFormat},
+	}
+	for _, tt := range tests {
+		sections, err := splitCFF([]byte(tt.content))
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(sections) != len(tt.kinds) {
+			t.Errorf("%s: %d sections, want %d", tt.name, len(sections), len(tt.kinds))
+			continue
+		}
+		for k, v := range sections {
+			if v.kind != tt.kinds[k] || string(v.content) != tt.texts[k] {
+				t.Errorf("%s: section %d %s %q, want %s %q", tt.name, k, v.kind, v.content, tt.kinds[k], tt.texts[k])
+			}
+		}
+	}
+}
+
+func TestReadCFF(t *testing.T) {
+	for _, name := range testRecords {
+		cfg := readTestRecord(t, name)
+		cfg.HeaderFileContent = []byte("header of " + name)
+		for _, format := range testFormats {
+			var buf bytes.Buffer
+			if err := cfg.WriteCFF(&buf, format); err != nil {
+				t.Fatal(err)
+			}
+			read := New()
+			if err := read.ReadCFF(&buf); err != nil {
+				t.Fatalf("%s %s: %v", name, format, err)
+			}
+			if read.GetDataFileType() != format || read.GetSamplingNumber() != cfg.GetSamplingNumber() {
+				t.Errorf("%s %s: %d samples of %s", name, format, read.GetSamplingNumber(), read.GetDataFileType())
+			}
+			if string(read.GetHeaderFileContent()) != "header of "+name+"\r\n" {
+				t.Errorf("%s %s: header %q", name, format, read.GetHeaderFileContent())
+			}
+			want, err := cfg.GetDigitalChannelData(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := read.GetDigitalChannelData(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s %s: digital channel 1 differs", name, format)
+			}
+		}
+	}
+}
@@ -2,7 +2,7 @@ package comgo
 
 import (
 	"bytes"
-	"encoding/binary"
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -14,6 +14,18 @@ import (
 
 const TimeFormat = "02/01/2006T15:04:05.000000"
 
+// Time references of the samples, see GetTimeOffsets
+const (
+	TimeSourceStamp = "STAMP" // time stamp of the data file, sampling rate if missing
+	TimeSourceRate  = "RATE"  // sampling rate, time stamp of the data file if the rate is 0
+)
+
+// Primary and secondary identifiers of analog channel values
+const (
+	ValuePrimary   = "P"
+	ValueSecondary = "S"
+)
+
 // New returns configuration parameters of COMTRADE files.
 func New() CFG {
 	return CFG{}
@@ -35,23 +47,51 @@ func New() CFG {
  * @TriggerTime: Date and time of trigger point
  * @DataFileType: Data file type
  * @TimeFactor: Time Stamp multiplication factor
+ * @TimeBase: Time Stamp base unit, microsecond or nanosecond (C37.111-2013)
+ * @TimeCode: Time code of the record, offset from UTC (C37.111-2013)
+ * @LocalCode: Local time offset from UTC, x if not used (C37.111-2013)
+ * @TimeQuality: Time quality code of the recorder clock (C37.111-2013)
+ * @LeapSecond: Leap second indicator (C37.111-2013)
  * @DataFileContent: Store data file content
+ * @HeaderFileContent: Store header file content
+ * @InfoFileContent: Store information file content
+ * @Encoding: Character encoding of .cfg and .hdr files, detected if empty
+ * @Workers: Number of goroutines decoding binary data file in ReadDAT, see DecodeParallel (one if 0)
+ * @TimeSource: Time reference of the samples, TimeSourceStamp (if empty) or TimeSourceRate
+ * @cfgEncoding: Character encoding of .cfg file read by ReadCFG
+ * @record: Data file content decoded by ReadDAT or the first channel accessor
+ * @recordContent: Data file content record was decoded from
+ * @mapped: Data file mapped in memory by OpenMapped
  */
 type CFG struct {
-	StationName     string
-	RecordDeviceId  string
-	RevisionYear    uint16
-	ChannelNumber   uint16
-	AnalogDetail    *ChannelA
-	DigitDetail     *ChannelD
-	LineFrequency   uint16
-	SampleRateNum   uint16
-	SampleDetail    []SampleRate
-	StartTime       time.Time
-	TriggerTime     time.Time
-	DataFileType    string
-	TimeFactor      float64
-	DataFileContent []byte
+	StationName       string
+	RecordDeviceId    string
+	RevisionYear      uint16
+	ChannelNumber     uint16
+	AnalogDetail      *ChannelA
+	DigitDetail       *ChannelD
+	LineFrequency     uint16
+	SampleRateNum     uint16
+	SampleDetail      []SampleRate
+	StartTime         time.Time
+	TriggerTime       time.Time
+	DataFileType      string
+	TimeFactor        float64
+	TimeBase          time.Duration
+	TimeCode          string
+	LocalCode         string
+	TimeQuality       uint8
+	LeapSecond        uint8
+	DataFileContent   []byte
+	HeaderFileContent []byte
+	InfoFileContent   []byte
+	Encoding          string
+	Workers           int
+	TimeSource        string
+	cfgEncoding       string
+	record            *Record
+	recordContent     []byte
+	mapped            *mapping
 }
 
 func (cfg *CFG) GetStationName() string {
@@ -145,6 +185,41 @@ func (cfg *CFG) GetTimeFactor() float64 {
 	return 0
 }
 
+func (cfg *CFG) GetTimeBase() time.Duration {
+	if cfg != nil && cfg.TimeBase > 0 {
+		return cfg.TimeBase
+	}
+	return time.Microsecond
+}
+
+func (cfg *CFG) GetTimeCode() string {
+	if cfg != nil {
+		return cfg.TimeCode
+	}
+	return ""
+}
+
+func (cfg *CFG) GetLocalCode() string {
+	if cfg != nil {
+		return cfg.LocalCode
+	}
+	return ""
+}
+
+func (cfg *CFG) GetTimeQuality() uint8 {
+	if cfg != nil {
+		return cfg.TimeQuality
+	}
+	return 0
+}
+
+func (cfg *CFG) GetLeapSecond() uint8 {
+	if cfg != nil {
+		return cfg.LeapSecond
+	}
+	return 0
+}
+
 func (cfg *CFG) GetDataFileContent() []byte {
 	if cfg != nil {
 		return cfg.DataFileContent
@@ -152,8 +227,51 @@ func (cfg *CFG) GetDataFileContent() []byte {
 	return nil
 }
 
-// Return the sampling rate
-// only one sampling rate is taking into account
+func (cfg *CFG) GetHeaderFileContent() []byte {
+	if cfg != nil {
+		return cfg.HeaderFileContent
+	}
+	return nil
+}
+
+func (cfg *CFG) GetInfoFileContent() []byte {
+	if cfg != nil {
+		return cfg.InfoFileContent
+	}
+	return nil
+}
+
+func (cfg *CFG) GetEncoding() string {
+	if cfg != nil {
+		return cfg.Encoding
+	}
+	return ""
+}
+
+func (cfg *CFG) GetWorkers() int {
+	if cfg != nil {
+		return cfg.Workers
+	}
+	return 0
+}
+
+func (cfg *CFG) GetTimeSource() string {
+	if cfg != nil && cfg.TimeSource != "" {
+		return cfg.TimeSource
+	}
+	return TimeSourceStamp
+}
+
+// Return the character encoding of .cfg file, selected or detected by ReadCFG
+func (cfg *CFG) GetCFGEncoding() string {
+	if cfg != nil {
+		return cfg.cfgEncoding
+	}
+	return ""
+}
+
+// Return the sampling rate of the first sampling segment
+// use GetSamplingRateAt for records with several sampling rates
 func (cfg *CFG) GetSamplingRate() float64 {
 	sampleDetail := cfg.GetSampleDetail()
 	if sampleDetail == nil || len(sampleDetail) == 0 {
@@ -162,17 +280,61 @@ func (cfg *CFG) GetSamplingRate() float64 {
 	return sampleDetail[0].GetRate()
 }
 
-// Return the number of samples
-// only one sampling rate is taking into account
+// Return the total number of samples
+// Number of each SampleRate is the last sample number (endsamp) of its segment
 func (cfg *CFG) GetSamplingNumber() int {
 	sampleDetail := cfg.GetSampleDetail()
 	if sampleDetail == nil || len(sampleDetail) == 0 {
 		return 0
 	}
-	return sampleDetail[0].GetNumber()
+	return sampleDetail[len(sampleDetail)-1].GetNumber()
+}
+
+// Return the index in SampleDetail of the sampling segment the i-th sample belongs to
+// return -1 if i is out of range
+func (cfg *CFG) GetSampleSegment(i int) int {
+	if i < 0 {
+		return -1
+	}
+	for k, v := range cfg.GetSampleDetail() {
+		if i < v.GetNumber() {
+			return k
+		}
+	}
+	return -1
+}
+
+// Return the sampling rate of the i-th sample
+// return 0 if i is out of range
+func (cfg *CFG) GetSamplingRateAt(i int) float64 {
+	if k := cfg.GetSampleSegment(i); k >= 0 {
+		return cfg.GetSampleDetail()[k].GetRate()
+	}
+	return 0
+}
+
+// Return the time offset of the i-th sample from the first sample
+// derived from the sampling rate of each segment the samples go through
+func (cfg *CFG) sampleOffset(i int) time.Duration {
+	var offset float64
+	start := 1 // the first sample is at offset 0
+	for _, v := range cfg.GetSampleDetail() {
+		if start > i {
+			break
+		}
+		end := v.GetNumber()
+		if end > i+1 {
+			end = i + 1
+		}
+		if v.GetRate() > 0 && end > start {
+			offset += float64(end-start) / v.GetRate()
+		}
+		start = v.GetNumber()
+	}
+	return time.Duration(math.Round(offset * float64(time.Second)))
 }
 
-// Return the names of all analog channel
+// Return the names of all analog channel, as written in .cfg file
 func (cfg *CFG) GetAnalogChannelNames() []string {
 	analogDetail := cfg.GetAnalogDetail()
 	if analogDetail != nil {
@@ -181,20 +343,32 @@ func (cfg *CFG) GetAnalogChannelNames() []string {
 	return nil
 }
 
+// Return the names of all digital channel, as written in .cfg file
+func (cfg *CFG) GetDigitalChannelNames() []string {
+	digitDetail := cfg.GetDigitDetail()
+	if digitDetail != nil {
+		return digitDetail.ChannelNames
+	}
+	return nil
+}
+
 /*
  * ChannelA - Analog channel parameters
  * @ChannelTotal: Total number of channels
  * @ChannelNumber: Channel number series
- * @ChannelNames: Names of each channel
- * @ChannelPhases: Phases of each channel
- * @ChannelElements: Channel element (usually null)
+ * @ChannelNames: Names of each channel, as written in .cfg file
+ * @ChannelPhases: Phases of each channel, as written in .cfg file
+ * @ChannelElements: Channel element (usually null), as written in .cfg file
  * @ChannelUnits: Units of each channel
  * @ConversionFactors: Conversion factor A and B
  * @TimeFactors: Time factors of each channels
- * @ValueMin: Min Value of each channels
- * @ValueMax: Max Value of each channels
+ * @ValueMin: Min Value of each channels, real values (FLOAT32 files) truncated to integer
+ * @ValueMax: Max Value of each channels, real values (FLOAT32 files) truncated to integer
+ * @ValueMinReal: Exact min value of each channels, as written in .cfg file
+ * @ValueMaxReal: Exact max value of each channels, as written in .cfg file
  * @Primary: Primary ratios
  * @Secondary: Secondary ratios
+ * @PS: Primary (P) or secondary (S) identifier of the values of each channel
  */
 type ChannelA struct {
 	ChannelTotal      uint16
@@ -207,8 +381,11 @@ type ChannelA struct {
 	TimeFactors       []float64
 	ValueMin          []int
 	ValueMax          []int
+	ValueMinReal      []float64
+	ValueMaxReal      []float64
 	Primary           []float64
 	Secondary         []float64
+	PS                []string
 }
 
 func (m *ChannelA) GetChannelTotal() uint16 {
@@ -281,6 +458,32 @@ func (m *ChannelA) GetValueMax() []int {
 	return nil
 }
 
+func (m *ChannelA) GetValueMinReal() []float64 {
+	if m != nil {
+		return m.ValueMinReal
+	}
+	return nil
+}
+
+func (m *ChannelA) GetValueMaxReal() []float64 {
+	if m != nil {
+		return m.ValueMaxReal
+	}
+	return nil
+}
+
+// Return the exact min and max values of the k-th channel (starting at 0)
+// from ValueMinReal and ValueMaxReal, or ValueMin and ValueMax if they are not set
+func (m *ChannelA) valueLimits(k int) (min, max float64, ok bool) {
+	if k < len(m.GetValueMinReal()) && k < len(m.GetValueMaxReal()) {
+		return m.ValueMinReal[k], m.ValueMaxReal[k], true
+	}
+	if k < len(m.GetValueMin()) && k < len(m.GetValueMax()) {
+		return float64(m.ValueMin[k]), float64(m.ValueMax[k]), true
+	}
+	return 0, 0, false
+}
+
 func (m *ChannelA) GetPrimary() []float64 {
 	if m != nil {
 		return m.Primary
@@ -295,13 +498,20 @@ func (m *ChannelA) GetSecondary() []float64 {
 	return nil
 }
 
+func (m *ChannelA) GetPS() []string {
+	if m != nil {
+		return m.PS
+	}
+	return nil
+}
+
 /*
  * ChannelD - Digit channel parameters
  * @ChannelTotal: Total number of channels
  * @ChannelNumber: Channel number series
- * @ChannelNames: Names of each channel
- * @ChannelPhases: Phases of each channel
- * @ChannelElements: Channel element (usually null)
+ * @ChannelNames: Names of each channel, as written in .cfg file
+ * @ChannelPhases: Phases of each channel, as written in .cfg file
+ * @ChannelElements: Channel element (usually null), as written in .cfg file
  */
 type ChannelD struct {
 	ChannelTotal    uint16
@@ -357,7 +567,7 @@ func (m *ChannelD) GetInitialState() []uint8 {
 /*
  * SampleRate - Sampling rate and sampling number
  * @Rate: Sampling rate
- * @Number: Total number under current sampling rate
+ * @Number: Last sample number (endsamp) under current sampling rate
  */
 type SampleRate struct {
 	Rate   float64
@@ -378,6 +588,39 @@ func (m *SampleRate) GetNumber() int {
 	return 0
 }
 
+/*
+ * DigitalEdge - State change of a digital channel
+ * @Index: Index of the sample where the state changed
+ * @Time: Time offset of the sample from StartTime
+ * @State: New state of the channel (0 or 1)
+ */
+type DigitalEdge struct {
+	Index int
+	Time  time.Duration
+	State uint8
+}
+
+func (m *DigitalEdge) GetIndex() int {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *DigitalEdge) GetTime() time.Duration {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *DigitalEdge) GetState() uint8 {
+	if m != nil {
+		return m.State
+	}
+	return 0
+}
+
 /*
  * BinData - Dat date structure
  * @Sample: Sample series
@@ -413,44 +656,77 @@ func (m *BinData) GetValue() []int16 {
 
 // Reads the Comtrade header file (.cfg).
 // return empty CFG and error if err != nil
+// Parsing errors are returned as *ParseError with the line and field of the error
 func (cfg *CFG) ReadCFG(rd io.Reader) (err error) {
 	var tempList [][]byte
 	content, err := ioutil.ReadAll(rd)
 	if err != nil {
 		return err
 	}
+	// The data file is decoded again according to the new configuration
+	cfg.record = nil
+	// Fields appended to or absent from some revisions, not kept from a previous read
+	cfg.RevisionYear, cfg.SampleDetail = 0, nil
+	cfg.TimeCode, cfg.LocalCode, cfg.TimeQuality, cfg.LeapSecond = "", "", 0, 0
+	// Decode to UTF-8 and LF line endings
+	content, cfg.cfgEncoding, err = decodeText(content, cfg.GetEncoding())
+	if err != nil {
+		return &ParseError{File: "CFG", Err: err}
+	}
 	lines := bytes.Split(content, []byte("\n"))
 
+	// Returns a ParseError if the file ends before line n
+	truncated := func(n int, name string) error {
+		if n >= len(lines) {
+			return cfgError(n, -1, name, nil, ErrTruncated)
+		}
+		return nil
+	}
+
 	// Processing first line
-	tempList = bytes.Split(lines[0], []byte(","))
+	n := 0
+	if err := truncated(n, "station name and recording device id"); err != nil {
+		return err
+	}
+	tempList = bytes.Split(lines[n], []byte(","))
 	if len(tempList) < 2 {
-		return errors.New("cfg format error")
+		return cfgError(n, -1, "station name and recording device id", lines[n], ErrFormat)
 	}
 	cfg.StationName = ByteToString(tempList[0])
 	cfg.RecordDeviceId = ByteToString(tempList[1])
 	// checking vector length to avoid IndexError
 	if len(tempList) > 2 {
 		if value, err := strconv.ParseUint(ByteToString(tempList[2]), 10, 16); err != nil {
-			return err
+			return cfgError(n, 2, "revision year", tempList[2], err)
 		} else {
 			cfg.RevisionYear = uint16(value)
 		}
 	}
+	// Layout rules of the revision: 1991, 1999 or 2013
+	rules := revisionRules(cfg.GetRevisionYear())
+	analogFields, digitFields := channelFields(rules)
 
 	// Processing second line
-	tempList = bytes.Split(lines[1], []byte(","))
+	n++
+	if err := truncated(n, "channel numbers"); err != nil {
+		return err
+	}
+	tempList = bytes.Split(lines[n], []byte(","))
 	if len(tempList) < 3 {
-		return errors.New("cfg format error")
+		return cfgError(n, -1, "channel numbers", lines[n], ErrFormat)
 	}
 	// Total channel number
 	if value, err := strconv.ParseUint(ByteToString(tempList[0]), 10, 16); err != nil {
-		return err
+		return cfgError(n, 0, "total channel number", tempList[0], err)
 	} else {
 		cfg.ChannelNumber = uint16(value)
 	}
 
-	if !bytes.Contains(tempList[1], []byte("A")) || !bytes.Contains(tempList[2], []byte("D")) {
-		return errors.New("cfg format error")
+	if !bytes.Contains(tempList[1], []byte("A")) {
+		return cfgError(n, 1, "analog channel number", tempList[1], ErrFormat)
+	}
+	if !bytes.Contains(tempList[2], []byte("D")) {
+		return cfgError(n, 2, "digital channel number", tempList[2], ErrFormat)
 	}
 
 	// Initialize analog and digit channels
@@ -460,133 +736,185 @@ func (cfg *CFG) ReadCFG(rd io.Reader) (err error) {
 
 	// Analog channel total number
 	if value, err := strconv.ParseUint(string(bytes.TrimSuffix(bytes.TrimSpace(tempList[1]), []byte("A"))), 10, 16); err != nil {
-		return err
+		return cfgError(n, 1, "analog channel number", tempList[1], err)
 	} else {
 		chA.ChannelTotal = uint16(value)
 	}
 
 	// Digit channel total number
 	if value, err := strconv.ParseUint(string(bytes.TrimSuffix(bytes.TrimSpace(tempList[2]), []byte("D"))), 10, 16); err != nil {
-		return err
+		return cfgError(n, 2, "digital channel number", tempList[2], err)
 	} else {
 		chD.ChannelTotal = uint16(value)
 	}
 
 	// Processing analog channels
 	for i := 0; i < int(chA.GetChannelTotal()); i++ {
-		tempList = bytes.Split(lines[2+i], []byte(","))
-		if len(tempList) < 10 {
-			return errors.New("cfg format error")
+		n++
+		name := "analog channel " + strconv.Itoa(i+1)
+		if err := truncated(n, name); err != nil {
+			return err
+		}
+		tempList = bytes.Split(lines[n], []byte(","))
+		if len(tempList) < analogFields {
+			return cfgError(n, -1, name, lines[n], ErrFormat)
 		}
 		if num, err := strconv.Atoi(ByteToString(tempList[0])); err != nil {
-			return err
+			return cfgError(n, 0, name+", number", tempList[0], err)
 		} else {
 			chA.ChannelNumber = append(chA.GetChannelNumber(), uint16(num))
 		}
-		// Format ids to xxx_xxx_xxx
-		chA.ChannelNames = append(chA.GetChannelNames(), ByteToString(bytes.Join(bytes.Split(tempList[1], []byte(" ")), []byte("_"))))
-		chA.ChannelPhases = append(chA.GetChannelPhases(), ByteToString(tempList[2]))
+		// Identifier, phase and circuit component are kept verbatim, see NameFormat
+		chA.ChannelNames = append(chA.GetChannelNames(), string(tempList[1]))
+		chA.ChannelPhases = append(chA.GetChannelPhases(), string(tempList[2]))
 		// Channel element (usually null)
-		chA.ChannelElements = append(chA.GetChannelElements(), ByteToString(tempList[3]))
+		chA.ChannelElements = append(chA.GetChannelElements(), string(tempList[3]))
 		chA.ChannelUnits = append(chA.GetChannelUnits(), ByteToString(tempList[4]))
 		// Conversion factor A
 		if num, err := strconv.ParseFloat(ByteToString(tempList[5]), 64); err != nil {
-			return err
+			return cfgError(n, 5, name+", factor a", tempList[5], err)
 		} else {
 			chA.ConversionFactors["a"] = append(chA.GetConversionFactors()["a"], num)
 		}
 		// Conversion factor B
 		if num, err := strconv.ParseFloat(ByteToString(tempList[6]), 64); err != nil {
-			return err
+			return cfgError(n, 6, name+", factor b", tempList[6], err)
 		} else {
 			chA.ConversionFactors["b"] = append(chA.GetConversionFactors()["b"], num)
 		}
 		// Time factor
 		if num, err := strconv.ParseFloat(ByteToString(tempList[7]), 64); err != nil {
-			return err
+			return cfgError(n, 7, name+", skew", tempList[7], err)
 		} else {
 			chA.TimeFactors = append(chA.GetTimeFactors(), num)
 		}
-		// Min Value at current channel
-		if num, err := strconv.Atoi(ByteToString(tempList[8])); err != nil {
-			return err
+		// Min Value at current channel (real for FLOAT32 files)
+		if num, real, err := parseLimit(tempList[8]); err != nil {
+			return cfgError(n, 8, name+", min", tempList[8], err)
 		} else {
 			chA.ValueMin = append(chA.GetValueMin(), num)
+			chA.ValueMinReal = append(chA.GetValueMinReal(), real)
 		}
-		// Max Value at current channel
-		if num, err := strconv.Atoi(ByteToString(tempList[9])); err != nil {
-			return err
+		// Max Value at current channel (real for FLOAT32 files)
+		if num, real, err := parseLimit(tempList[9]); err != nil {
+			return cfgError(n, 9, name+", max", tempList[9], err)
 		} else {
 			chA.ValueMax = append(chA.GetValueMax(), num)
+			chA.ValueMaxReal = append(chA.GetValueMaxReal(), real)
 		}
 
-		if len(tempList) > 10 {
-			if num, err := strconv.ParseFloat(ByteToString(tempList[10]), 64); err == nil {
-				chA.Primary = append(chA.GetPrimary(), num)
-			}
+		// The 1991 revision has no primary, secondary and PS fields
+		if rules == Revision1991 {
+			chA.PS = append(chA.GetPS(), "")
+			continue
 		}
-		if len(tempList) > 11 {
-			if num, err := strconv.ParseFloat(ByteToString(tempList[11]), 64); err == nil {
-				chA.Secondary = append(chA.GetSecondary(), num)
-			}
+		if num, err := strconv.ParseFloat(ByteToString(tempList[10]), 64); err != nil {
+			return cfgError(n, 10, name+", primary", tempList[10], err)
+		} else {
+			chA.Primary = append(chA.GetPrimary(), num)
+		}
+		if num, err := strconv.ParseFloat(ByteToString(tempList[11]), 64); err != nil {
+			return cfgError(n, 11, name+", secondary", tempList[11], err)
+		} else {
+			chA.Secondary = append(chA.GetSecondary(), num)
+		}
+		// Primary or secondary identifier
+		if ps := strings.ToUpper(ByteToString(tempList[12])); ps != ValuePrimary && ps != ValueSecondary {
+			return cfgError(n, 12, name+", PS", tempList[12], ErrFormat)
+		} else {
+			chA.PS = append(chA.GetPS(), ps)
 		}
 	}
 
 	// Processing digit channels
 	for i := 0; i < int(chD.GetChannelTotal()); i++ {
-		tempList = bytes.Split(lines[2+int(chA.GetChannelTotal())+i], []byte(","))
-		if len(tempList) < 3 {
-			return errors.New("cfg format error")
+		n++
+		name := "digital channel " + strconv.Itoa(i+1)
+		if err := truncated(n, name); err != nil {
+			return err
+		}
+		tempList = bytes.Split(lines[n], []byte(","))
+		if len(tempList) < digitFields {
+			return cfgError(n, -1, name, lines[n], ErrFormat)
 		}
 		if num, err := strconv.Atoi(ByteToString(tempList[0])); err != nil {
-			return err
+			return cfgError(n, 0, name+", number", tempList[0], err)
 		} else {
 			chD.ChannelNumber = append(chD.GetChannelNumber(), uint16(num))
 		}
-		chD.ChannelNames = append(chD.GetChannelNames(), ByteToString(bytes.Join(bytes.Split(tempList[1], []byte(" ")), []byte("_"))))
-		chD.ChannelPhases = append(chD.GetChannelPhases(), ByteToString(tempList[2]))
-
-		// checking vector length to avoid IndexError
-		if len(tempList) > 3 {
+		// Identifier, phase and circuit component are kept verbatim, see NameFormat
+		chD.ChannelNames = append(chD.GetChannelNames(), string(tempList[1]))
+
+		// 1991: Dn,ch_id,y - 1999 and 2013: Dn,ch_id,ph,ccbm,y
+		state := 2
+		if rules == Revision1991 {
+			chD.ChannelPhases = append(chD.GetChannelPhases(), "")
+			chD.ChannelElements = append(chD.GetChannelElements(), "")
+		} else {
+			chD.ChannelPhases = append(chD.GetChannelPhases(), string(tempList[2]))
 			// Channel element (usually null)
-			chD.ChannelElements = append(chD.GetChannelElements(), ByteToString(tempList[3]))
+			chD.ChannelElements = append(chD.GetChannelElements(), string(tempList[3]))
+			state = 4
 		}
-		if len(tempList) > 4 {
-			if num, err := strconv.ParseUint(ByteToString(tempList[4]), 10, 8); err != nil {
-				return err
-			} else {
-				chD.InitialState = append(chD.GetInitialState(), uint8(num))
-			}
+		if num, err := strconv.ParseUint(ByteToString(tempList[state]), 10, 8); err != nil {
+			return cfgError(n, state, name+", normal state", tempList[state], err)
+		} else {
+			chD.InitialState = append(chD.GetInitialState(), uint8(num))
 		}
 	}
 
 	// Read line frequency
-	tempList = bytes.Split(lines[2+chA.GetChannelTotal()+chD.GetChannelTotal()], []byte(","))
-	if num, err := strconv.ParseFloat(ByteToString(tempList[0]), 64); err != nil {
+	n++
+	if err := truncated(n, "line frequency"); err != nil {
 		return err
+	}
+	tempList = bytes.Split(lines[n], []byte(","))
+	if num, err := strconv.ParseFloat(ByteToString(tempList[0]), 64); err != nil {
+		return cfgError(n, 0, "line frequency", tempList[0], err)
 	} else {
 		cfg.LineFrequency = uint16(num)
 	}
 
 	// Read sampling rate num
-	tempList = bytes.Split(lines[3+chA.GetChannelTotal()+chD.GetChannelTotal()], []byte(","))
-	if num, err := strconv.ParseUint(ByteToString(tempList[0]), 10, 16); err != nil {
+	n++
+	if err := truncated(n, "number of sampling rates"); err != nil {
 		return err
+	}
+	tempList = bytes.Split(lines[n], []byte(","))
+	if num, err := strconv.ParseUint(ByteToString(tempList[0]), 10, 16); err != nil {
+		return cfgError(n, 0, "number of sampling rates", tempList[0], err)
 	} else {
 		cfg.SampleRateNum = uint16(num)
 	}
 
-	// Read Sample number (@TODO only one sampling rate is taking into account)
-	for i := 0; i < int(cfg.GetSampleRateNum()); i++ {
+	// Read sampling rate and last sample number (endsamp) of each segment
+	// a file without fixed sampling rate (nrates = 0) still has one "0,endsamp" line
+	rateLines := cfg.GetSampleRateNum()
+	if rateLines == 0 {
+		rateLines = 1
+	}
+	for i := 0; i < int(rateLines); i++ {
+		n++
+		name := "sampling rate " + strconv.Itoa(i+1)
 		sampleRate := SampleRate{}
-		tempList = bytes.Split(lines[4+i+int(chA.GetChannelTotal())+int(chD.GetChannelTotal())], []byte(","))
-		if num, err := strconv.ParseFloat(ByteToString(tempList[0]), 64); err != nil {
+		if err := truncated(n, name); err != nil {
 			return err
+		}
+		tempList = bytes.Split(lines[n], []byte(","))
+		if len(tempList) < 2 {
+			return cfgError(n, -1, name, lines[n], ErrFormat)
+		}
+		if num, err := strconv.ParseFloat(ByteToString(tempList[0]), 64); err != nil {
+			return cfgError(n, 0, name+", samp", tempList[0], err)
+		} else if num < 0 || math.IsInf(num, 0) || math.IsNaN(num) {
+			return cfgError(n, 0, name+", samp", tempList[0], ErrFormat)
 		} else {
 			sampleRate.Rate = num
 		}
 		if num, err := strconv.ParseFloat(ByteToString(tempList[1]), 64); err != nil {
-			return err
+			return cfgError(n, 1, name+", endsamp", tempList[1], err)
+		} else if !(num >= 0 && num <= math.MaxInt32) {
+			return cfgError(n, 1, name+", endsamp", tempList[1], ErrFormat)
 		} else {
 			sampleRate.Number = int(num)
 		}
@@ -594,59 +922,160 @@ func (cfg *CFG) ReadCFG(rd io.Reader) (err error) {
 	}
 
 	// Read start date and time ([dd,mm,yyyy,hh,mm,ss.ssssss])
-	tempList = bytes.Split(lines[4+cfg.GetSampleRateNum()+chA.GetChannelTotal()+chD.GetChannelTotal()], []byte(","))
-	if start, err := time.Parse(TimeFormat, ByteToString(bytes.Join(tempList, []byte("T")))); err != nil {
+	n++
+	if err := truncated(n, "start date and time"); err != nil {
 		return err
+	}
+	if start, nano, err := parseCFGTime(lines[n], rules); err != nil {
+		return cfgError(n, -1, "start date and time", lines[n], err)
 	} else {
-		cfg.TriggerTime = start
+		cfg.StartTime = start
+		// 2013: time stamps are in nanoseconds if the dates have nanosecond resolution
+		if rules == Revision2013 && nano {
+			cfg.TimeBase = time.Nanosecond
+		} else {
+			cfg.TimeBase = time.Microsecond
+		}
 	}
 
 	// Read trigger date and time ([dd,mm,yyyy,hh,mm,ss.ssssss])
-	tempList = bytes.Split(lines[5+cfg.GetSampleRateNum()+chA.GetChannelTotal()+chD.GetChannelTotal()], []byte(","))
-	if trigger, err := time.Parse(TimeFormat, ByteToString(bytes.Join(tempList, []byte("T")))); err != nil {
+	n++
+	if err := truncated(n, "trigger date and time"); err != nil {
 		return err
+	}
+	if trigger, _, err := parseCFGTime(lines[n], rules); err != nil {
+		return cfgError(n, -1, "trigger date and time", lines[n], err)
 	} else {
-		cfg.StartTime = trigger
+		cfg.TriggerTime = trigger
 	}
 
 	// Read dat content type
-	tempList = bytes.Split(lines[6+cfg.GetSampleRateNum()+chA.GetChannelTotal()+chD.GetChannelTotal()], []byte(","))
+	n++
+	if err := truncated(n, "data file type"); err != nil {
+		return err
+	}
+	tempList = bytes.Split(lines[n], []byte(","))
 	cfg.DataFileType = ByteToString(tempList[0])
 
-	// Read time multiplication factor
-	tempList = bytes.Split(lines[7+cfg.GetSampleRateNum()+chA.GetChannelTotal()+chD.GetChannelTotal()], []byte(","))
-	if !bytes.Equal(tempList[0], []byte("")) {
-		if num, err := strconv.ParseFloat(ByteToString(tempList[0]), 64); err != nil {
+	// Read time multiplication factor (not in the 1991 revision)
+	cfg.TimeFactor = 1
+	if rules != Revision1991 {
+		n++
+		if err := truncated(n, "time multiplication factor"); err != nil {
 			return err
+		}
+		tempList = bytes.Split(lines[n], []byte(","))
+		if !bytes.Equal(tempList[0], []byte("")) {
+			if num, err := strconv.ParseFloat(ByteToString(tempList[0]), 64); err != nil {
+				return cfgError(n, 0, "time multiplication factor", tempList[0], err)
+			} else {
+				cfg.TimeFactor = num
+			}
 		} else {
-			cfg.TimeFactor = num
+			cfg.TimeFactor = 1
+		}
+	}
+
+	// Read time code and local code (C37.111-2013)
+	n++
+	if rules == Revision2013 && n < len(lines) && len(bytes.TrimSpace(lines[n])) > 0 {
+		tempList = bytes.Split(lines[n], []byte(","))
+		if len(tempList) < 2 {
+			return cfgError(n, -1, "time code and local code", lines[n], ErrFormat)
+		}
+		cfg.TimeCode = ByteToString(tempList[0])
+		cfg.LocalCode = ByteToString(tempList[1])
+		// Start and trigger times are recorded in the time zone of time code
+		loc, err := parseTimeCode(cfg.GetTimeCode())
+		if err != nil {
+			return cfgError(n, 0, "time code", tempList[0], err)
+		}
+		cfg.StartTime = inZone(cfg.StartTime, loc)
+		cfg.TriggerTime = inZone(cfg.TriggerTime, loc)
+		if code := cfg.GetLocalCode(); code != "" && !strings.EqualFold(code, "x") {
+			if _, err := parseTimeCode(code); err != nil {
+				return cfgError(n, 1, "local code", tempList[1], err)
+			}
+		}
+	}
+
+	// Read time quality code and leap second indicator (C37.111-2013)
+	n++
+	if rules == Revision2013 && n < len(lines) && len(bytes.TrimSpace(lines[n])) > 0 {
+		tempList = bytes.Split(lines[n], []byte(","))
+		if len(tempList) < 2 {
+			return cfgError(n, -1, "time quality code and leap second", lines[n], ErrFormat)
+		}
+		if num, err := strconv.ParseUint(ByteToString(tempList[0]), 16, 4); err != nil {
+			return cfgError(n, 0, "time quality code", tempList[0], err)
+		} else {
+			cfg.TimeQuality = uint8(num)
+		}
+		if num, err := strconv.ParseUint(ByteToString(tempList[1]), 10, 2); err != nil {
+			return cfgError(n, 1, "leap second", tempList[1], err)
+		} else {
+			cfg.LeapSecond = uint8(num)
 		}
-	} else {
-		cfg.TimeFactor = 1
 	}
 
 	return nil
 }
 
 // Reads the contents of the Comtrade .dat file
-// Store the contents in a private variable
+// Store the contents and decode them into a Record if .cfg has been read
 func (cfg *CFG) ReadDAT(rd io.Reader) (err error) {
-	content, err := ioutil.ReadAll(rd)
+	return cfg.ReadDATContext(context.Background(), rd)
+}
+
+// Reads the contents of the Comtrade .dat file like ReadDAT
+// Reading and decoding stop when ctx is done, returning a CancelError
+func (cfg *CFG) ReadDATContext(ctx context.Context, rd io.Reader) (err error) {
+	content, err := ioutil.ReadAll(&contextReader{ctx, rd})
 	if err != nil {
+		if ctx.Err() != nil {
+			return &CancelError{Samples: 0, Err: ctx.Err()}
+		}
+		return err
+	}
+	// Release the data file previously mapped by OpenMapped
+	if err := cfg.Close(); err != nil {
 		return err
 	}
 	cfg.DataFileContent = content
+	cfg.record = nil
+
+	// Decode the whole content once if the configuration is known
+	if cfg.GetAnalogDetail() != nil {
+		decode := cfg.DecodeContext
+		if cfg.GetWorkers() > 1 {
+			decode = func(ctx context.Context) (*Record, error) { return cfg.DecodeParallelContext(ctx, cfg.GetWorkers()) }
+		}
+		record, err := decode(ctx)
+		if err != nil {
+			return err
+		}
+		cfg.setRecord(record)
+	}
 	return nil
 }
 
 // Returns an array of numbers containing the data values of the channel number
 // num is the number of the channel as in .cfg file
+// The data file is decoded according to DataFileType (ASCII, BINARY, BINARY32 or FLOAT32)
+// Missing samples are NaN, see GetAnalogChannelFlags
 func (cfg *CFG) GetAnalogChannelData(num uint16) (result []float64, err error) {
+	return cfg.GetAnalogChannelDataContext(context.Background(), num)
+}
+
+// Returns the data values of the channel number like GetAnalogChannelData
+// Decoding stops when ctx is done, returning a CancelError
+func (cfg *CFG) GetAnalogChannelDataContext(ctx context.Context, num uint16) (result []float64, err error) {
 	if cfg == nil {
 		return nil, errors.New("invalid cfg file, read .cfg first")
 	}
 
-	if cfg.GetDataFileContent() == nil || bytes.Equal(cfg.GetDataFileContent(), []byte("")) {
+	// A record set by SetRecord or Rescale has no data file content
+	if cfg.record == nil && len(cfg.GetDataFileContent()) == 0 {
 		return nil, errors.New("not data content, read .dat first")
 	}
 
@@ -663,54 +1092,199 @@ func (cfg *CFG) GetAnalogChannelData(num uint16) (result []float64, err error) {
 		return nil, errors.New("analog channel number cannot be less than 1")
 	}
 
+	column, err := cfg.analogColumn(ctx, int(num-1))
+	if err != nil {
+		return nil, err
+	}
+
+	factor := analogDetail.GetConversionFactors()
+	if len(factor["a"]) < int(num) || len(factor["b"]) < int(num) {
+		return nil, errors.New("invalid conversion factors")
+	}
+	a, b := factor["a"][num-1], factor["b"][num-1]
+
+	result = make([]float64, len(column))
+	for i, v := range column {
+		result[i] = v*a + b
+	}
+
+	return result, nil
+}
+
+// Returns the data values of the channel number in primary (P) or secondary (S) quantities
+// The values are converted with the primary and secondary ratios if the PS identifier
+// of the channel differs from ps, a channel without identifier is taken as primary
+func (cfg *CFG) GetAnalogChannelDataPS(num uint16, ps string) (result []float64, err error) {
+	ps = strings.ToUpper(strings.TrimSpace(ps))
+	if ps != ValuePrimary && ps != ValueSecondary {
+		return nil, errors.New("ps must be P or S")
+	}
+
+	result, err = cfg.GetAnalogChannelData(num)
+	if err != nil {
+		return nil, err
+	}
+
+	analogDetail := cfg.GetAnalogDetail()
+	from := ValuePrimary
+	if k := int(num) - 1; k < len(analogDetail.GetPS()) && analogDetail.GetPS()[k] == ValueSecondary {
+		from = ValueSecondary
+	}
+	if from == ps {
+		return result, nil
+	}
+
+	k := int(num) - 1
+	if k >= len(analogDetail.GetPrimary()) || k >= len(analogDetail.GetSecondary()) {
+		return nil, errors.New("primary or secondary ratio not available")
+	}
+	primary, secondary := analogDetail.GetPrimary()[k], analogDetail.GetSecondary()[k]
+	if primary == 0 || secondary == 0 {
+		return nil, errors.New("primary or secondary ratio cannot be 0")
+	}
+
+	ratio := primary / secondary
+	if ps == ValueSecondary {
+		ratio = secondary / primary
+	}
+	for i := range result {
+		result[i] *= ratio
+	}
+
+	return result, nil
+}
+
+// Returns the states (0 or 1) of the digital channel number at each sample
+// num is the number of the channel as in .cfg file
+func (cfg *CFG) GetDigitalChannelData(num uint16) (result []uint8, err error) {
+	return cfg.GetDigitalChannelDataContext(context.Background(), num)
+}
+
+// Returns the states of the digital channel number like GetDigitalChannelData
+// Decoding stops when ctx is done, returning a CancelError
+func (cfg *CFG) GetDigitalChannelDataContext(ctx context.Context, num uint16) (result []uint8, err error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cfg file, read .cfg first")
+	}
+
 	digitDetail := cfg.GetDigitDetail()
 	if digitDetail == nil {
 		return nil, errors.New("invalid digital channel")
 	}
 
-	// Number of bytes per Sample:
-	NB := 8 + int(analogDetail.GetChannelTotal())<<1 + int(math.Ceil(float64(int(digitDetail.GetChannelTotal()))/float64(16)))<<1
+	if num > digitDetail.GetChannelTotal() {
+		return nil, errors.New("digital channel number greater than the total number of channels")
+	}
 
-	sampleDetail := cfg.GetSampleDetail()
-	if sampleDetail == nil || len(sampleDetail) == 0 {
-		return nil, errors.New("invalid or not enough sample detail")
+	if num < 1 {
+		return nil, errors.New("digital channel number cannot be less than 1")
 	}
 
-	dataFileContent := cfg.GetDataFileContent()
-	if dataFileContent == nil {
-		return nil, errors.New("invalid dat file content")
+	states, n, err := cfg.digitColumn(ctx, int(num-1))
+	if err != nil {
+		return nil, err
 	}
 
-	factor := analogDetail.GetConversionFactors()
+	result = make([]uint8, n)
+	for i := range result {
+		result[i] = states.Get(i)
+	}
 
-	// Number of samples: @TODO - only take 1 rate into account
-	// Reading the values from datFileContent string
-	for i := 0; i < sampleDetail[0].GetNumber(); i++ {
-		s := dataFileContent[i*NB : i*NB+NB]
+	return result, nil
+}
 
-		var data struct {
-			Sample int32
-			Stamp  int32
-		}
+// Returns the state changes of the digital channel number
+// the state of the first sample is taken as the reference state
+func (cfg *CFG) GetDigitalChannelEdges(num uint16) (edges []DigitalEdge, err error) {
+	states, err := cfg.GetDigitalChannelData(num)
+	if err != nil {
+		return nil, err
+	}
 
-		value := make([]int16, (NB-8)/2) // dynamic slice
+	offsets, err := cfg.GetTimeOffsets()
+	if err != nil {
+		return nil, err
+	}
 
-		err = binary.Read(bytes.NewReader(s[:8]), binary.LittleEndian, &data)
-		if err != nil {
-			return nil, err
+	for i := 1; i < len(states); i++ {
+		if states[i] != states[i-1] {
+			edges = append(edges, DigitalEdge{Index: i, Time: offsets[i], State: states[i]})
 		}
+	}
 
-		err = binary.Read(bytes.NewReader(s[8:]), binary.LittleEndian, &value)
-		if err != nil {
-			return nil, err
-		}
+	return edges, nil
+}
+
+// Returns the time offset of each sample from StartTime
+// The time stamp of the sample multiplied by TimeFactor (in TimeBase units) is used,
+// if the time stamp is missing (0xFFFFFFFF or empty) or TimeFactor is 0
+// the offset is derived from the sampling rates
+// With TimeSource set to TimeSourceRate the sampling rates are used whenever the rate of
+// the sample is known (time stamps are non-critical then), time stamps only if it is 0
+func (cfg *CFG) GetTimeOffsets() (result []time.Duration, err error) {
+	stamps, err := cfg.stampColumn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	result = make([]time.Duration, len(stamps))
+	for i, v := range stamps {
+		result[i] = cfg.stampOffset(i, v)
+	}
+
+	return result, nil
+}
 
-		result = append(result, float64(value[num-1])*factor["a"][num-1]+factor["b"][num-1])
+// Return the time offset of the i-th sample whose time stamp is stamp
+// according to TimeSource, see GetTimeOffsets
+func (cfg *CFG) stampOffset(i int, stamp uint32) time.Duration {
+	if cfg.usesStamp(i) && stamp != missingStamp {
+		return time.Duration(math.Round(float64(stamp) * cfg.GetTimeFactor() * float64(cfg.GetTimeBase())))
+	}
+	return cfg.sampleOffset(i)
+}
+
+// Check if the time offset of the i-th sample is taken from its time stamp when present
+func (cfg *CFG) usesStamp(i int) bool {
+	if cfg.GetTimeFactor() <= 0 {
+		return false
+	}
+	if cfg.GetTimeSource() == TimeSourceRate {
+		return cfg.GetSampleRateNum() == 0 || cfg.GetSamplingRateAt(i) <= 0
+	}
+	return true
+}
+
+// Returns the date and time of each sample
+// see GetTimeOffsets for how the time of each sample is computed
+func (cfg *CFG) GetTimestamps() (result []time.Time, err error) {
+	offsets, err := cfg.GetTimeOffsets()
+	if err != nil {
+		return nil, err
+	}
+
+	start := cfg.GetStartTime()
+	result = make([]time.Time, len(offsets))
+	for i, v := range offsets {
+		result[i] = start.Add(v)
 	}
 
 	return result, nil
 }
 
+// Parses min or max value of analog channel
+// return the value truncated to integer and the exact value, real for FLOAT32 files
+func parseLimit(b []byte) (int, float64, error) {
+	if num, err := strconv.Atoi(ByteToString(b)); err == nil {
+		return num, float64(num), nil
+	}
+	num, err := strconv.ParseFloat(ByteToString(b), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(math.Max(math.Min(num, math.MaxInt32), math.MinInt32)), num, nil
+}
+
 // Convert []byte type file content to string
 // Delete extra space
 func ByteToString(b []byte) string {
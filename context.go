@@ -0,0 +1,55 @@
+package comgo
+
+import (
+	"context"
+	"io"
+)
+
+// Number of samples decoded between two checks of the context
+const contextCheck = 1024
+
+// Return a CancelError if ctx is done, checked every contextCheck samples
+// i is the number of samples decoded so far
+func checkContext(ctx context.Context, i int) error {
+	if i%contextCheck != 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return &CancelError{Samples: i, Err: err}
+	}
+	return nil
+}
+
+// contextReader - Reader failing with the error of its context once it is done
+type contextReader struct {
+	ctx context.Context
+	rd  io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.rd.Read(p)
+}
+
+// Reads the contents of the Comtrade .cfg file like ReadCFG
+// Reading stops when ctx is done, returning a CancelError
+func (cfg *CFG) ReadCFGContext(ctx context.Context, rd io.Reader) error {
+	err := cfg.ReadCFG(&contextReader{ctx, rd})
+	if err != nil && ctx.Err() != nil {
+		return &CancelError{Samples: 0, Err: ctx.Err()}
+	}
+	return err
+}
+
+// Return a reader decoding the samples of the data file rd like NewSampleReader
+// Next returns a CancelError once ctx is done
+func (cfg *CFG) NewSampleReaderContext(ctx context.Context, rd io.Reader) (*SampleReader, error) {
+	sr, err := cfg.NewSampleReader(&contextReader{ctx, rd})
+	if err != nil {
+		return nil, err
+	}
+	sr.ctx = ctx
+	return sr, nil
+}
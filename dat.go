@@ -0,0 +1,278 @@
+package comgo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Data file types
+const (
+	DataFileASCII    = "ASCII"
+	DataFileBinary   = "BINARY"
+	DataFileBinary32 = "BINARY32"
+	DataFileFloat32  = "FLOAT32"
+)
+
+// Time stamp value used when the stamp of a sample is missing
+const missingStamp = 0xFFFFFFFF
+
+// Analog values of missing samples in BINARY and BINARY32 data files
+const (
+	missingBinary   = 0x8000
+	missingBinary32 = 0x80000000
+)
+
+/*
+ * record - One decoded sample of the data file
+ * @sample: Sample number
+ * @stamp: Time stamp
+ * @analog: Raw analog values (NaN if missing)
+ * @digit: Digit channel states (0 or 1)
+ */
+type record struct {
+	sample uint32
+	stamp  uint32
+	analog []float64
+	digit  []uint8
+}
+
+// Return the normalized data file type
+func (cfg *CFG) dataFileType() string {
+	return normalizeDataFileType(cfg.GetDataFileType())
+}
+
+// Normalize data file type to upper case
+// empty type is treated as BINARY
+func normalizeDataFileType(t string) string {
+	t = strings.ToUpper(strings.TrimSpace(t))
+	if t == "" {
+		return DataFileBinary
+	}
+	return t
+}
+
+// Number of bytes of each analog value in binary data file
+func (cfg *CFG) analogSize() int {
+	return analogSizeOf(cfg.dataFileType())
+}
+
+// Number of bytes of each analog value in binary data file of type format
+func analogSizeOf(format string) int {
+	switch format {
+	case DataFileBinary32, DataFileFloat32:
+		return 4
+	default:
+		return 2
+	}
+}
+
+// Number of bytes per sample of binary data file
+func (cfg *CFG) recordSize() int {
+	nA := int(cfg.GetAnalogDetail().GetChannelTotal())
+	nD := int(cfg.GetDigitDetail().GetChannelTotal())
+	return 8 + nA*cfg.analogSize() + int(math.Ceil(float64(nD)/float64(16)))<<1
+}
+
+// Check the configuration and the data file content can be decoded
+// the content must hold the number of samples of the configuration
+func (cfg *CFG) checkData() error {
+	if cfg == nil {
+		return errors.New("invalid cfg file, read .cfg first")
+	}
+	if len(cfg.GetDataFileContent()) == 0 {
+		return errors.New("not data content, read .dat first")
+	}
+	if cfg.GetAnalogDetail() == nil {
+		return errors.New("invalid analog channel")
+	}
+	if cfg.GetDigitDetail() == nil {
+		return errors.New("invalid digital channel")
+	}
+	if len(cfg.GetSampleDetail()) == 0 {
+		return errors.New("invalid or not enough sample detail")
+	}
+
+	content, num := cfg.GetDataFileContent(), cfg.GetSamplingNumber()
+	if num < 0 {
+		return errors.New("invalid or not enough sample detail")
+	}
+	switch cfg.dataFileType() {
+	case DataFileASCII:
+		// At least one line per sample, of at least a sample number digit and one comma
+		// per field, so that the columns are not allocated for samples the content cannot hold
+		if lines := bytes.Count(content, []byte("\n")) + 1; lines < num {
+			return datError(lines, len(content), -1, "", nil, ErrTruncated)
+		}
+		minLine := 3 + int(cfg.GetAnalogDetail().GetChannelTotal()) + int(cfg.GetDigitDetail().GetChannelTotal())
+		if max := (len(content) + 1) / minLine; max < num {
+			return datError(max, len(content), -1, "", nil, ErrTruncated)
+		}
+	case DataFileBinary, DataFileBinary32, DataFileFloat32:
+		if NB := cfg.recordSize(); len(content)/NB < num {
+			i := len(content) / NB
+			return datError(i, i*NB, -1, "", nil, ErrTruncated)
+		}
+	default:
+		return errors.New("unsupported data file type: " + cfg.GetDataFileType())
+	}
+	return nil
+}
+
+// Decodes every sample of the data file content and calls fn for each of them
+// the record passed to fn is reused between calls
+func (cfg *CFG) scanRecords(fn func(i int, r *record) error) error {
+	if err := cfg.checkData(); err != nil {
+		return err
+	}
+
+	r := record{
+		analog: make([]float64, cfg.GetAnalogDetail().GetChannelTotal()),
+		digit:  make([]uint8, cfg.GetDigitDetail().GetChannelTotal()),
+	}
+
+	if cfg.dataFileType() == DataFileASCII {
+		return cfg.scanASCII(&r, fn)
+	}
+	return cfg.scanBinary(&r, fn)
+}
+
+// Decodes binary data file content
+// [n(4), timestamp(4), A1..Ak(2 or 4 each), D1..Dm(2 each, 16 channels per word)]
+// BINARY: int16, BINARY32: int32, FLOAT32: IEEE 754 float32 analog values
+func (cfg *CFG) scanBinary(r *record, fn func(i int, r *record) error) error {
+	content := cfg.GetDataFileContent()
+	num := cfg.GetSamplingNumber()
+	NB := cfg.recordSize()
+	format, size := cfg.dataFileType(), cfg.analogSize()
+
+	for i := 0; i < num; i++ {
+		r.decodeBinary(content[i*NB:i*NB+NB], format, size)
+		if err := fn(i, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decodes one sample of binary data file
+// s holds the recordSize bytes of the sample
+func (r *record) decodeBinary(s []byte, format string, size int) {
+	r.sample = binary.LittleEndian.Uint32(s[0:4])
+	r.stamp = binary.LittleEndian.Uint32(s[4:8])
+	for k := range r.analog {
+		r.analog[k] = binaryAnalog(s, k, format, size)
+	}
+	s = s[8+len(r.analog)*size:]
+	for k := range r.digit {
+		r.digit[k] = binaryDigit(s, k)
+	}
+}
+
+// Decodes the raw value of the k-th analog channel of one binary sample
+// missing values (0x8000, 0x80000000 or NaN) are NaN
+func binaryAnalog(s []byte, k int, format string, size int) float64 {
+	s = s[8+k*size:]
+	switch format {
+	case DataFileBinary32:
+		if v := binary.LittleEndian.Uint32(s); v != missingBinary32 {
+			return float64(int32(v))
+		}
+		return math.NaN()
+	case DataFileFloat32:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(s)))
+	default:
+		if v := binary.LittleEndian.Uint16(s); v != missingBinary {
+			return float64(int16(v))
+		}
+		return math.NaN()
+	}
+}
+
+// Decodes the state of the k-th digital channel
+// s holds the digital words of one binary sample
+func binaryDigit(s []byte, k int) uint8 {
+	return uint8(binary.LittleEndian.Uint16(s[(k>>4)<<1:]) >> uint(k&15) & 1)
+}
+
+// Decodes ASCII data file content
+// n,timestamp,A1,...,Ak,D1,...,Dm per line, empty field means missing value
+func (cfg *CFG) scanASCII(r *record, fn func(i int, r *record) error) error {
+	content := cfg.GetDataFileContent()
+	num := cfg.GetSamplingNumber()
+
+	i, offset := 0, 0
+	for len(content) > 0 && i < num {
+		var line []byte
+		start := offset
+		if end := bytes.IndexByte(content, '\n'); end >= 0 {
+			line, content = content[:end], content[end+1:]
+			offset += end + 1
+		} else {
+			line, content = content, nil
+			offset += len(line)
+		}
+		line = bytes.TrimSpace(line)
+		// Skip blank lines and the end of file marker (0x1A)
+		if len(line) == 0 || (len(line) == 1 && line[0] == 0x1A) {
+			continue
+		}
+
+		if err := r.decodeASCII(line, i, start); err != nil {
+			return err
+		}
+		if err := fn(i, r); err != nil {
+			return err
+		}
+		i++
+	}
+
+	if i < num {
+		return datError(i, offset, -1, "", nil, ErrTruncated)
+	}
+	return nil
+}
+
+// Decodes one line of ASCII data file
+// i and offset locate the line in the data file for errors
+func (r *record) decodeASCII(line []byte, i, offset int) error {
+	tempList := bytes.Split(line, []byte(","))
+	if len(tempList) < 2+len(r.analog)+len(r.digit) {
+		return datError(i, offset, -1, "", line, ErrFormat)
+	}
+	if value, err := strconv.ParseUint(ByteToString(tempList[0]), 10, 32); err != nil {
+		return datError(i, offset, 0, "sample number", tempList[0], err)
+	} else {
+		r.sample = uint32(value)
+	}
+	if stamp := ByteToString(tempList[1]); stamp == "" {
+		r.stamp = missingStamp
+	} else if value, err := strconv.ParseUint(stamp, 10, 32); err != nil {
+		return datError(i, offset, 1, "time stamp", tempList[1], err)
+	} else {
+		r.stamp = uint32(value)
+	}
+	for k := range r.analog {
+		if value := ByteToString(tempList[2+k]); value == "" {
+			r.analog[k] = math.NaN()
+		} else if num, err := strconv.ParseFloat(value, 64); err != nil {
+			return datError(i, offset, 2+k, "analog channel "+strconv.Itoa(k+1), tempList[2+k], err)
+		} else {
+			r.analog[k] = num
+		}
+	}
+	for k := range r.digit {
+		field := 2 + len(r.analog) + k
+		if value := ByteToString(tempList[field]); value == "" {
+			r.digit[k] = 0
+		} else if num, err := strconv.ParseUint(value, 10, 8); err != nil {
+			return datError(i, offset, field, "digital channel "+strconv.Itoa(k+1), tempList[field], err)
+		} else {
+			r.digit[k] = uint8(num) & 1
+		}
+	}
+	return nil
+}
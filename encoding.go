@@ -0,0 +1,117 @@
+package comgo
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// Character encodings of .cfg and .hdr files
+// GBK is a superset of GB2312 and Windows-1252 of the printable characters of Latin-1
+const (
+	EncodingUTF8        = "UTF-8"
+	EncodingLatin1      = "ISO-8859-1"
+	EncodingWindows1252 = "Windows-1252"
+	EncodingGBK         = "GBK"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Return the decoder of the encoding, nil for UTF-8
+func textEncoding(name string) (encoding.Encoding, error) {
+	switch strings.ToUpper(strings.Replace(name, "_", "-", -1)) {
+	case "UTF-8", "UTF8":
+		return nil, nil
+	case "ISO-8859-1", "LATIN-1", "LATIN1":
+		return charmap.ISO8859_1, nil
+	case "WINDOWS-1252", "CP1252":
+		return charmap.Windows1252, nil
+	case "GBK", "GB2312", "CP936":
+		return simplifiedchinese.GBK, nil
+	default:
+		return nil, errors.New("unsupported character encoding: " + name)
+	}
+}
+
+// Minimum number of GB2312 characters of text detected as GBK
+const minGB2312 = 2
+
+// Guess the encoding of text without byte order mark
+// Valid UTF-8 is UTF-8, text made of GBK double-byte characters mostly in the
+// GB2312 range, with at least two of them in a row, is GBK, anything else is Windows-1252
+// Accented Latin letters such as "ÇÃ" of "SUBESTAÇÃO" also form GB2312 pairs, but
+// alone between ASCII letters, while Chinese words are runs of several characters
+func detectEncoding(text []byte) string {
+	if utf8.Valid(text) {
+		return EncodingUTF8
+	}
+	pairs, gb2312, run, longest := 0, 0, 0, 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c < 0x80 {
+			run = 0
+			continue
+		}
+		// GBK lead byte 0x81-0xFE, trail byte 0x40-0xFE except 0x7F
+		if c == 0x80 || c == 0xFF || i+1 >= len(text) {
+			return EncodingWindows1252
+		}
+		t := text[i+1]
+		if t < 0x40 || t == 0x7F || t == 0xFF {
+			return EncodingWindows1252
+		}
+		pairs++
+		if c >= 0xA1 && t >= 0xA1 {
+			gb2312++
+			if run++; run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+		i++
+	}
+	if gb2312 >= minGB2312 && gb2312*4 >= pairs*3 && longest >= 2 {
+		return EncodingGBK
+	}
+	return EncodingWindows1252
+}
+
+// Decodes text of .cfg or .hdr file to UTF-8 with LF line endings
+// The encoding is detected if name is empty, a UTF-8 byte order mark always selects UTF-8
+// Returns the decoded text and the name of the encoding
+func decodeText(text []byte, name string) ([]byte, string, error) {
+	if bytes.HasPrefix(text, utf8BOM) {
+		text, name = text[len(utf8BOM):], EncodingUTF8
+	} else if name == "" {
+		name = detectEncoding(text)
+	}
+	enc, err := textEncoding(name)
+	if err != nil {
+		return nil, name, err
+	}
+	if enc != nil {
+		if text, err = enc.NewDecoder().Bytes(text); err != nil {
+			return nil, name, err
+		}
+	} else if !utf8.Valid(text) {
+		// Invalid sequences are replaced so names are always valid UTF-8
+		text = bytes.ToValidUTF8(text, []byte(string(utf8.RuneError)))
+	}
+	// CRLF and CR line endings
+	text = bytes.Replace(text, []byte("\r\n"), []byte("\n"), -1)
+	text = bytes.Replace(text, []byte("\r"), []byte("\n"), -1)
+	return text, name, nil
+}
+
+// Return the header file content decoded to UTF-8 with LF line endings
+// HeaderFileContent is decoded with the encoding of the record, or the detected one if not set
+func (cfg *CFG) GetHeaderText() (string, error) {
+	text, _, err := decodeText(cfg.GetHeaderFileContent(), cfg.GetEncoding())
+	return string(text), err
+}
@@ -0,0 +1,115 @@
+package comgo
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// Encodes text of the test cases to the encoding
+func encodeText(t *testing.T, name, text string) []byte {
+	t.Helper()
+	switch name {
+	case EncodingWindows1252:
+		b, err := charmap.Windows1252.NewEncoder().Bytes([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	case EncodingLatin1:
+		b, err := charmap.ISO8859_1.NewEncoder().Bytes([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	case EncodingGBK:
+		b, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	return []byte(text)
+}
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+		want     string
+	}{
+		{EncodingUTF8, "SUBESTAÇÃO ITAIPU,1,1999", EncodingUTF8},
+		{EncodingUTF8, "Station 1,REL670,2013", EncodingUTF8},
+		{EncodingWindows1252, "SUBESTAÇÃO ITAIPU,1,1999", EncodingWindows1252},
+		{EncodingWindows1252, "SUBESTAÇÃO SÃO JOÃO,PROTEÇÃO DE TENSÃO,1999", EncodingWindows1252},
+		{EncodingWindows1252, "1,CORREÇÃO,,,A\n2,PROTEÇÃO,,,A\n3,ATUAÇÃO,,,A", EncodingWindows1252},
+		{EncodingWindows1252, "Umspannwerk Zürich – Süd,1,1999", EncodingWindows1252},
+		{EncodingLatin1, "Poste électrique Évry,1,1999", EncodingWindows1252},
+		{EncodingGBK, "北京变电站,1号故障录波器,1999", EncodingGBK},
+		{EncodingGBK, "1,主变高压侧A相电流,A,,kA", EncodingGBK},
+	}
+	for _, tt := range tests {
+		if got := detectEncoding(encodeText(t, tt.encoding, tt.text)); got != tt.want {
+			t.Errorf("%q in %s: detected %s, want %s", tt.text, tt.encoding, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		encoding string
+		want     string
+		wantEnc  string
+	}{
+		{"UTF-8 with BOM", append([]byte{0xEF, 0xBB, 0xBF}, "SUBESTAÇÃO,1\r\n2013"...), "", "SUBESTAÇÃO,1\n2013", EncodingUTF8},
+		{"BOM overrides encoding", append([]byte{0xEF, 0xBB, 0xBF}, "变电站\n"...), EncodingGBK, "变电站\n", EncodingUTF8},
+		{"CR line endings", []byte("a,b\rc,d\re"), "", "a,b\nc,d\ne", EncodingUTF8},
+		{"CRLF line endings", []byte("a,b\r\nc,d\r\n"), "", "a,b\nc,d\n", EncodingUTF8},
+		{"Windows-1252", encodeText(t, EncodingWindows1252, "SUBESTAÇÃO ITAIPU\r\n"), "", "SUBESTAÇÃO ITAIPU\n", EncodingWindows1252},
+		{"Latin-1 selected", encodeText(t, EncodingLatin1, "Évry\r"), EncodingLatin1, "Évry\n", EncodingLatin1},
+		{"GBK", encodeText(t, EncodingGBK, "北京变电站\r\n"), "", "北京变电站\n", EncodingGBK},
+		{"invalid UTF-8 selected", []byte("a\xffb"), EncodingUTF8, "a�b", EncodingUTF8},
+	}
+	for _, tt := range tests {
+		got, enc, err := decodeText(tt.content, tt.encoding)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want || enc != tt.wantEnc {
+			t.Errorf("%s: got %q in %s, want %q in %s", tt.name, got, enc, tt.want, tt.wantEnc)
+		}
+	}
+
+	if _, _, err := decodeText([]byte("a"), "EBCDIC"); err == nil {
+		t.Error("unsupported encoding accepted")
+	}
+}
+
+func TestReadCFGEncoding(t *testing.T) {
+	content, err := os.ReadFile("examples/data/test1.cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Station name of test1 replaced by an accented Windows-1252 name, CR line endings
+	lines := strings.SplitN(string(content), "\n", 2)
+	text := append(encodeText(t, EncodingWindows1252, "SUBESTAÇÃO ITAIPU,1,1999\r"), lines[1]...)
+	text = bytes.Replace(text, []byte("\r\n"), []byte("\r"), -1)
+
+	cfg := New()
+	if err := cfg.ReadCFG(bytes.NewReader(text)); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GetStationName() != "SUBESTAÇÃO ITAIPU" || cfg.GetCFGEncoding() != EncodingWindows1252 {
+		t.Errorf("station name %q in %s", cfg.GetStationName(), cfg.GetCFGEncoding())
+	}
+	if cfg.GetAnalogDetail().GetChannelTotal() != 20 {
+		t.Errorf("%d analog channels, want 20", cfg.GetAnalogDetail().GetChannelTotal())
+	}
+}
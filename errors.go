@@ -0,0 +1,92 @@
+package comgo
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Errors wrapped by ParseError
+var (
+	ErrFormat    = errors.New("format error")
+	ErrTruncated = errors.New("unexpected end of data")
+)
+
+/*
+ * ParseError - Position and cause of a COMTRADE parsing error
+ * @File: Kind of the file (CFG, DAT or CFF)
+ * @Line: Line number, starting at 1 (0 if unknown)
+ * @Field: Field index in the line or record, starting at 1 (0 if unknown)
+ * @Name: Name of the field, e.g. "analog channel 7, factor a"
+ * @Text: Raw text of the field
+ * @Record: Sample record of the data file, starting at 1 (0 if unknown)
+ * @Offset: Byte offset of the record in the data file
+ * @Err: Underlying error
+ */
+type ParseError struct {
+	File   string
+	Line   int
+	Field  int
+	Name   string
+	Text   string
+	Record int
+	Offset int64
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(e.File))
+	if e.Line > 0 {
+		b.WriteString(" line " + strconv.Itoa(e.Line))
+	}
+	if e.Record > 0 {
+		b.WriteString(" record " + strconv.Itoa(e.Record) + " (offset " + strconv.FormatInt(e.Offset, 10) + ")")
+	}
+	if e.Field > 0 {
+		b.WriteString(" field " + strconv.Itoa(e.Field))
+	}
+	if e.Name != "" {
+		b.WriteString(" (" + e.Name + ")")
+	}
+	if e.Text != "" {
+		b.WriteString(" " + strconv.Quote(e.Text))
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Return a ParseError of the .cfg file
+// line and field are indexes starting at 0, field < 0 if not applicable
+func cfgError(line, field int, name string, text []byte, err error) error {
+	return &ParseError{File: "CFG", Line: line + 1, Field: field + 1, Name: name, Text: ByteToString(text), Err: err}
+}
+
+// Return a ParseError of the .dat file
+// record and field are indexes starting at 0, field < 0 if not applicable
+func datError(record int, offset int, field int, name string, text []byte, err error) error {
+	return &ParseError{File: "DAT", Record: record + 1, Offset: int64(offset), Field: field + 1, Name: name, Text: ByteToString(text), Err: err}
+}
+
+/*
+ * CancelError - Reading or decoding stopped because its context is done
+ * @Samples: Number of samples decoded before stopping
+ * @Err: Error of the context, context.Canceled or context.DeadlineExceeded
+ */
+type CancelError struct {
+	Samples int
+	Err     error
+}
+
+func (e *CancelError) Error() string {
+	return "stopped after " + strconv.Itoa(e.Samples) + " samples: " + e.Err.Error()
+}
+
+func (e *CancelError) Unwrap() error {
+	return e.Err
+}
@@ -10,7 +10,6 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 )
 
 var data [][]string
@@ -57,12 +56,14 @@ func main() {
 		Help()
 	}
 
-	file, err := os.Open(flagFile)
-	defer file.Close()
-	CheckError(err)
-
-	cfg := comgo.New()
-	err = cfg.ReadCFG(file)
+	var cfg *comgo.CFG
+	if flagDetail && strings.EqualFold(filepath.Ext(flagFile), ".cfg") {
+		// Channel names only need the .cfg file, the .dat file may be absent
+		cfg, err = ReadConfig(flagFile)
+	} else {
+		// Any file of the record, the others are found next to it
+		cfg, err = comgo.Open(flagFile)
+	}
 	CheckError(err)
 
 	if flagDetail {
@@ -70,37 +71,23 @@ func main() {
 		os.Exit(1)
 	}
 
-	var filename string
-
-	name := strings.TrimSuffix(flagFile, filepath.Ext(flagFile))
-	if exist := PathExists(name + ".dat"); exist {
-		filename = name + ".dat"
-	} else if exist := PathExists(name + ".DAT"); exist {
-		filename = name + ".DAT"
-	} else {
-		log.Fatal("Data file File not found.")
-	}
-
-	file, err = os.Open(filename)
-	defer file.Close()
-	CheckError(err)
-	err = cfg.ReadDAT(file)
-	CheckError(err)
+	// Time stamps of some recorders wrap around, the sampling rates are used instead
+	cfg.TimeSource = comgo.TimeSourceRate
 
 	res, err := cfg.GetAnalogChannelData(uint16(flagChannel))
 	CheckError(err)
 
-	ra := cfg.GetSamplingRate()
-	ti := cfg.GetStartTime()
+	ti, err := cfg.GetTimestamps()
+	CheckError(err)
 
-	for i := 0; i < cfg.GetSamplingNumber(); i++ {
-		s, _ := time.ParseDuration(strconv.FormatFloat(float64(i)/float64(ra), 'g', 1, 64) + "s")
-		x := ti.Add(s).Format(AxisFormat)
+	for i := range res {
+		x := ti[i].Format(AxisFormat)
 		y := strconv.FormatFloat(res[i], 'f', -1, 32)
 		data = append(data, []string{x, y})
 	}
 
-	file, err = os.OpenFile(name+".csv", os.O_CREATE|os.O_WRONLY, 0777)
+	name := strings.TrimSuffix(flagFile, filepath.Ext(flagFile))
+	file, err := os.OpenFile(name+".csv", os.O_CREATE|os.O_WRONLY, 0777)
 	defer file.Close()
 	CheckError(err)
 
@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"github.com/ValleyZw/comgo"
 	"log"
 	"os"
 	"strings"
@@ -10,13 +11,6 @@ import (
 
 const AxisFormat = "2006-01-02T15:04:05.000Z"
 
-func PathExists(filePath string) bool {
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return false
-	}
-	return true
-}
-
 func CommandLine(args []string) ([]string, error) {
 	// command line
 	if len(args) < 1 {
@@ -41,6 +35,21 @@ func CheckError(err error) {
 	}
 }
 
+// Read the .cfg file alone
+func ReadConfig(name string) (*comgo.CFG, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cfg := comgo.New()
+	if err := cfg.ReadCFG(file); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
 func Header() {
 	fmt.Println(`
    ____   U  ___ u  __  __     ____    U  ___ u 
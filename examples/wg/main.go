@@ -1,16 +1,15 @@
 package main
 
 import (
+	"encoding/json"
 	"github.com/ValleyZw/comgo"
 	"html/template"
+	"io"
 	"log"
+	"math"
 	"mime/multipart"
 	"net/http"
-	"path/filepath"
-	"strconv"
-	"strings"
-	"sync"
-	"time"
+	"runtime"
 )
 
 // Channels and points
@@ -35,8 +34,18 @@ type Points struct {
 
 // Data point
 type Point struct {
-	X []string  `json:"x"`
-	Y []float64 `json:"y"`
+	X []string `json:"x"`
+	Y []Value  `json:"y"`
+}
+
+// Value of a data point, null if missing (NaN)
+type Value float64
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(float64(v))
 }
 
 var entry Entry
@@ -66,69 +75,62 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 
 		//get a ref to the parsed multipart form
 		files := r.MultipartForm.File
-		fileNames := make(map[string]*multipart.FileHeader)
+		headers := make(map[string]*multipart.FileHeader)
+		var names []string
 		for _, v := range files {
-			fileNames[strings.ToLower(filepath.Ext(v[0].Filename))] = v[0]
+			headers[v[0].Filename] = v[0]
+			names = append(names, v[0].Filename)
 		}
 
+		// Group the uploaded files by record, .cfg and .dat files are required
+		records := comgo.GroupFiles(names)
+		if len(records) == 0 {
+			http.Error(w, ".cfg file not exist", http.StatusBadRequest)
+			return
+		}
 		cfg := comgo.New()
+		cfg.Workers = runtime.NumCPU()
+		// Time stamps of some recorders wrap around, the sampling rates are used instead
+		cfg.TimeSource = comgo.TimeSourceRate
 		entry = Entry{}
-
-		if cfgFile, ok := fileNames[".cfg"]; ok {
-			file, err := cfgFile.Open()
-			defer file.Close()
-			if err != nil {
-				log.Println(err)
-				return
-			}
-			err = cfg.ReadCFG(file)
-			if err != nil {
-				log.Println(err)
-				return
-			}
-		} else {
-			http.Error(w, ".cfg file not exist", http.StatusInternalServerError)
+		err = cfg.ReadRecordContext(r.Context(), &records[0], func(name string) (io.ReadCloser, error) {
+			return headers[name].Open()
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		if dat, ok := fileNames[".dat"]; ok {
-			file, err := dat.Open()
-			defer file.Close()
-			if err != nil {
-				log.Println(err)
-				return
-			}
-			cfg.ReadDAT(file)
-		} else {
-			http.Error(w, ".dat file not exist", http.StatusInternalServerError)
+		ti, err := cfg.GetTimestamps()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		ra := cfg.GetSamplingRate()
-		ti := cfg.GetStartTime()
-
 		var t []string
-		for i := 0; i < cfg.GetSamplingNumber(); i++ {
-			s, _ := time.ParseDuration(strconv.FormatFloat(float64(i)/float64(ra), 'g', 1, 64) + "s")
-			t = append(t, ti.Add(s).Format(AxisFormat))
+		for _, v := range ti {
+			t = append(t, v.Format(AxisFormat))
 		}
 
-		wg := sync.WaitGroup{}
-		names := cfg.GetAnalogChannelNames()
-		wg.Add(len(names))
-		for k, v := range names {
-			go func(k int, v string) {
-				defer wg.Done()
-				points, err := cfg.GetAnalogChannelData(uint16(k + 1))
-				if err != nil {
-					log.Println(err)
+		// The data file is decoded once by ReadDAT on all cores, channel accessors only scale columns
+		// Names are used as element ids, spaces are replaced
+		for k, v := range cfg.GetAnalogChannelNamesFormat(comgo.DefaultNameFormat) {
+			points, err := cfg.GetAnalogChannelDataContext(r.Context(), uint16(k+1))
+			if err != nil {
+				// The client is gone, stop decoding
+				if r.Context().Err() != nil {
 					return
 				}
-				anaPoints := Points{v, "line", Point{t, points}}
-				entry.AnalogIds = append(entry.AnalogIds, IDs{v, v, anaPoints})
-			}(k, v)
+				log.Println(err)
+				continue
+			}
+			values := make([]Value, len(points))
+			for i, p := range points {
+				values[i] = Value(p)
+			}
+			anaPoints := Points{v, "line", Point{t, values}}
+			entry.AnalogIds = append(entry.AnalogIds, IDs{v, v, anaPoints})
 		}
-		wg.Wait()
 	}
 	err := temp.ExecuteTemplate(w, "index.html", &entry)
 	if err != nil {
@@ -0,0 +1,52 @@
+package comgo
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// Flags of analog channel samples returned by GetAnalogChannelFlags
+const (
+	SampleMissing = 1 << iota // missing value: 0x8000, 0x80000000 or empty ASCII field
+	SampleClipped             // raw value at or beyond ValueMin or ValueMax of the channel
+)
+
+// Returns the flags of each sample of the analog channel number
+// Samples at the exact limits of the channel (ValueMinReal, ValueMaxReal) are flagged as clipped,
+// e.g. saturation of the current transformer or of the converter
+// No sample is flagged as clipped if the limits are not set (ValueMin >= ValueMax)
+func (cfg *CFG) GetAnalogChannelFlags(num uint16) (result []uint8, err error) {
+	analogDetail := cfg.GetAnalogDetail()
+	if analogDetail == nil {
+		return nil, errors.New("invalid analog channel")
+	}
+
+	if num > analogDetail.GetChannelTotal() {
+		return nil, errors.New("analog channel number greater than the total number of channels")
+	}
+
+	if num < 1 {
+		return nil, errors.New("analog channel number cannot be less than 1")
+	}
+
+	column, err := cfg.analogColumn(context.Background(), int(num-1))
+	if err != nil {
+		return nil, err
+	}
+
+	min, max, clipping := analogDetail.valueLimits(int(num - 1))
+	clipping = clipping && min < max
+
+	result = make([]uint8, len(column))
+	for i, v := range column {
+		switch {
+		case math.IsNaN(v):
+			result[i] = SampleMissing
+		case clipping && (v <= min || v >= max):
+			result[i] = SampleClipped
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package comgo
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// Number of samples of the data files seeding FuzzDecode
+const fuzzSamples = 64
+
+func FuzzReadCFG(f *testing.F) {
+	for _, name := range testRecords {
+		content, err := os.ReadFile("examples/data/" + name + ".cfg")
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(content)
+	}
+	f.Fuzz(func(t *testing.T, content []byte) {
+		cfg := New()
+		if err := cfg.ReadCFG(bytes.NewReader(content)); err != nil {
+			return
+		}
+		cfg.Validate()
+		cfg.WriteCFG(&bytes.Buffer{})
+	})
+}
+
+func FuzzDecode(f *testing.F) {
+	// The first samples of each record of examples/data, in ASCII and binary
+	for _, name := range testRecords {
+		cfg := readTestRecord(f, name)
+		cfg.SampleRateNum = 1
+		cfg.SampleDetail = []SampleRate{{Rate: cfg.GetSamplingRate(), Number: fuzzSamples}}
+		content := readTestDAT(f, name)[:fuzzSamples*cfg.recordSize()]
+		cfg.DataFileContent = content
+		for _, format := range []string{DataFileASCII, DataFileBinary} {
+			out, err := cfg.Rescale(format)
+			if err != nil {
+				f.Fatal(err)
+			}
+			var cfgBuf, datBuf bytes.Buffer
+			if err := out.WriteCFG(&cfgBuf); err != nil {
+				f.Fatal(err)
+			}
+			if err := out.WriteDAT(&datBuf, format); err != nil {
+				f.Fatal(err)
+			}
+			f.Add(cfgBuf.Bytes(), datBuf.Bytes())
+		}
+	}
+	f.Fuzz(func(t *testing.T, cfgContent, datContent []byte) {
+		cfg := New()
+		if err := cfg.ReadCFG(bytes.NewReader(cfgContent)); err != nil {
+			return
+		}
+		if err := cfg.ReadDAT(bytes.NewReader(datContent)); err != nil {
+			return
+		}
+		for num := uint16(0); num <= cfg.GetAnalogDetail().GetChannelTotal()+1; num++ {
+			cfg.GetAnalogChannelData(num)
+			cfg.GetAnalogChannelFlags(num)
+		}
+		for num := uint16(0); num <= cfg.GetDigitDetail().GetChannelTotal()+1; num++ {
+			cfg.GetDigitalChannelEdges(num)
+		}
+		cfg.GetTimestamps()
+		cfg.Validate()
+	})
+}
@@ -0,0 +1,5 @@
+module github.com/ValleyZw/comgo
+
+go 1.18
+
+require golang.org/x/text v0.14.0
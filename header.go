@@ -0,0 +1,52 @@
+package comgo
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Longest key of a header line taken as a field
+const maxHeaderKey = 64
+
+// Reads the contents of the Comtrade .hdr file
+// The free text is stored as is in HeaderFileContent, see GetHeaderText and GetHeaderFields
+func (cfg *CFG) ReadHDR(rd io.Reader) (err error) {
+	content, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return err
+	}
+	cfg.HeaderFileContent = content
+	return nil
+}
+
+// Returns the fields of the header file written as "key: value" or "key = value" lines
+// e.g. relay model, firmware, fault type or distance, best effort as the header is free text
+// Keys and values are trimmed, keys hold a letter, the first line of a key is kept
+func (cfg *CFG) GetHeaderFields() (map[string]string, error) {
+	text, err := cfg.GetHeaderText()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		// The first separator splits the line, full width colon of Chinese text included
+		k := strings.IndexAny(line, ":=：")
+		if k < 0 {
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(line[k:])
+		key, value := strings.TrimSpace(line[:k]), strings.TrimSpace(line[k+size:])
+		// Times (12:30:45) and numbers are not keys
+		if strings.IndexFunc(key, unicode.IsLetter) < 0 || utf8.RuneCountInString(key) > maxHeaderKey {
+			continue
+		}
+		if _, ok := fields[key]; !ok {
+			fields[key] = value
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,108 @@
+package comgo
+
+import (
+	"errors"
+	"os"
+	"runtime/debug"
+	"unsafe"
+)
+
+/*
+ * mapping - Data file mapped in memory by OpenMapped
+ * @file: Mapped file, kept open to check its size
+ * @data: Mapped content
+ */
+type mapping struct {
+	file *os.File
+	data []byte
+}
+
+// Maps the data file at path in memory instead of reading it
+// DataFileContent is the mapping itself, nothing is decoded until a channel accessor is called
+// and binary channels are then decoded one at a time straight from the mapping
+// The mapping must be released with Close, DataFileContent must not be used after that
+func (cfg *CFG) OpenMapped(path string) error {
+	if cfg == nil {
+		return errors.New("invalid cfg file, read .cfg first")
+	}
+	if err := cfg.Close(); err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if info.Size() == 0 {
+		file.Close()
+		return errors.New("not data content, empty data file")
+	}
+	if int64(int(info.Size())) != info.Size() {
+		file.Close()
+		return errors.New("data file too large to be mapped")
+	}
+	data, err := mmapFile(file, int(info.Size()))
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	cfg.mapped = &mapping{file: file, data: data}
+	cfg.DataFileContent = data
+	cfg.record = nil
+	return nil
+}
+
+// Releases the data file mapped by OpenMapped, does nothing if there is none
+func (cfg *CFG) Close() error {
+	if cfg == nil || cfg.mapped == nil {
+		return nil
+	}
+	m := cfg.mapped
+	cfg.mapped = nil
+	cfg.DataFileContent = nil
+	cfg.record = nil
+	err := munmapFile(m.data)
+	if cerr := m.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Check if the data file content is mapped in memory
+func (cfg *CFG) IsMapped() bool {
+	return cfg != nil && cfg.mapped != nil
+}
+
+// Runs fn reading the data file content
+// If the content is mapped, the file must not have shrunk and a fault caused by a
+// file truncated while fn runs is returned as an error instead of crashing
+func (cfg *CFG) guardMapped(fn func() error) (err error) {
+	if cfg == nil || cfg.mapped == nil {
+		return fn()
+	}
+	m := cfg.mapped
+	if info, err := m.file.Stat(); err != nil {
+		return err
+	} else if info.Size() < int64(len(m.data)) {
+		return &ParseError{File: "DAT", Offset: info.Size(), Err: ErrTruncated}
+	}
+
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if r := recover(); r != nil {
+			if fault, ok := r.(interface{ Addr() uintptr }); ok {
+				offset := int64(fault.Addr() - uintptr(unsafe.Pointer(&m.data[0])))
+				err = &ParseError{File: "DAT", Offset: offset, Err: ErrTruncated}
+				return
+			}
+			panic(r)
+		}
+	}()
+	return fn()
+}
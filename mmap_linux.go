@@ -0,0 +1,15 @@
+package comgo
+
+import (
+	"os"
+	"syscall"
+)
+
+// Maps size bytes of file read-only
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package comgo
+
+import (
+	"errors"
+	"os"
+)
+
+// Memory-mapped data files are only supported on Linux
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	return nil, errors.New("memory-mapped data files are only supported on Linux")
+}
+
+func munmapFile(data []byte) error {
+	return nil
+}
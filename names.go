@@ -0,0 +1,132 @@
+package comgo
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Case folding of NameFormat
+const (
+	CaseKeep = iota
+	CaseUpper
+	CaseLower
+)
+
+/*
+ * NameFormat - Normalization of channel identifiers, ReadCFG keeps them verbatim
+ * @Trim: Remove leading and trailing white space
+ * @Separator: Replacement of each white space character, names are left as is if empty
+ * @Collapse: Replace runs of white space by a single separator
+ * @Case: Case folding, CaseKeep, CaseUpper or CaseLower
+ */
+type NameFormat struct {
+	Trim      bool
+	Separator string
+	Collapse  bool
+	Case      int
+}
+
+// Format of channel names before identifiers were kept verbatim ("LINE SUM ILA" to "LINE_SUM_ILA")
+var DefaultNameFormat = NameFormat{Trim: true, Separator: "_"}
+
+func (m *NameFormat) GetTrim() bool {
+	if m != nil {
+		return m.Trim
+	}
+	return false
+}
+
+func (m *NameFormat) GetSeparator() string {
+	if m != nil {
+		return m.Separator
+	}
+	return ""
+}
+
+func (m *NameFormat) GetCollapse() bool {
+	if m != nil {
+		return m.Collapse
+	}
+	return false
+}
+
+func (m *NameFormat) GetCase() int {
+	if m != nil {
+		return m.Case
+	}
+	return CaseKeep
+}
+
+// Return the name normalized according to the format
+func (m *NameFormat) Normalize(name string) string {
+	if m.GetTrim() {
+		name = strings.TrimSpace(name)
+	}
+	if sep := m.GetSeparator(); sep != "" {
+		var b strings.Builder
+		space := false
+		for _, r := range name {
+			if !unicode.IsSpace(r) {
+				b.WriteRune(r)
+				space = false
+				continue
+			}
+			if !space || !m.GetCollapse() {
+				b.WriteString(sep)
+			}
+			space = true
+		}
+		name = b.String()
+	}
+	switch m.GetCase() {
+	case CaseUpper:
+		name = strings.ToUpper(name)
+	case CaseLower:
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// Return the names normalized according to the format
+func (m *NameFormat) NormalizeAll(names []string) []string {
+	if names == nil {
+		return nil
+	}
+	list := make([]string, len(names))
+	for k, v := range names {
+		list[k] = m.Normalize(v)
+	}
+	return list
+}
+
+// Return the names of all analog channel normalized according to the format
+func (cfg *CFG) GetAnalogChannelNamesFormat(format NameFormat) []string {
+	return format.NormalizeAll(cfg.GetAnalogChannelNames())
+}
+
+// Return the names of all digital channel normalized according to the format
+func (cfg *CFG) GetDigitalChannelNamesFormat(format NameFormat) []string {
+	return format.NormalizeAll(cfg.GetDigitalChannelNames())
+}
+
+// Return the number of the first analog channel whose normalized name equals
+// the name normalized the same way, 0 if there is none
+func (cfg *CFG) FindAnalogChannel(name string, format NameFormat) uint16 {
+	return findChannel(cfg.GetAnalogChannelNames(), name, format)
+}
+
+// Return the number of the first digital channel whose normalized name equals
+// the name normalized the same way, 0 if there is none
+func (cfg *CFG) FindDigitalChannel(name string, format NameFormat) uint16 {
+	return findChannel(cfg.GetDigitalChannelNames(), name, format)
+}
+
+func findChannel(names []string, name string, format NameFormat) uint16 {
+	name = format.Normalize(name)
+	for k, v := range names {
+		if format.Normalize(v) == name {
+			return uint16(k + 1)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,219 @@
+package comgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Extensions of the files of a record
+var recordExtensions = []string{".cfg", ".dat", ".hdr", ".inf", ".cff"}
+
+/*
+ * RecordFiles - Names of the files of one record, empty if absent
+ * @Name: Name of the record, path of its files without extension
+ * @CFG: Configuration file
+ * @DAT: Data file
+ * @HDR: Header file
+ * @INF: Information file
+ * @CFF: Combined file (C37.111-2013)
+ */
+type RecordFiles struct {
+	Name string
+	CFG  string
+	DAT  string
+	HDR  string
+	INF  string
+	CFF  string
+}
+
+func (m *RecordFiles) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *RecordFiles) GetCFG() string {
+	if m != nil {
+		return m.CFG
+	}
+	return ""
+}
+
+func (m *RecordFiles) GetDAT() string {
+	if m != nil {
+		return m.DAT
+	}
+	return ""
+}
+
+func (m *RecordFiles) GetHDR() string {
+	if m != nil {
+		return m.HDR
+	}
+	return ""
+}
+
+func (m *RecordFiles) GetINF() string {
+	if m != nil {
+		return m.INF
+	}
+	return ""
+}
+
+func (m *RecordFiles) GetCFF() string {
+	if m != nil {
+		return m.CFF
+	}
+	return ""
+}
+
+// Split name into the record name and the lower case extension of a record file
+// ext is empty if name is not a record file
+func splitRecordName(name string) (stem, ext string) {
+	ext = strings.ToLower(path.Ext(name))
+	for _, v := range recordExtensions {
+		if ext == v {
+			return name[:len(name)-len(ext)], ext
+		}
+	}
+	return name, ""
+}
+
+// Groups file names by record, names without extension compared case-insensitively
+// Only records with a .cfg or .cff file are returned, sorted by name
+func GroupFiles(names []string) []RecordFiles {
+	groups := make(map[string]*RecordFiles)
+	for _, name := range names {
+		stem, ext := splitRecordName(name)
+		if ext == "" {
+			continue
+		}
+		key := strings.ToLower(stem)
+		group, ok := groups[key]
+		if !ok {
+			group = &RecordFiles{Name: stem}
+			groups[key] = group
+		}
+		switch ext {
+		case ".cfg":
+			group.CFG = name
+		case ".dat":
+			group.DAT = name
+		case ".hdr":
+			group.HDR = name
+		case ".inf":
+			group.INF = name
+		case ".cff":
+			group.CFF = name
+		}
+	}
+
+	var list []RecordFiles
+	for _, v := range groups {
+		if v.CFG != "" || v.CFF != "" {
+			list = append(list, *v)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name)
+	})
+	return list
+}
+
+// Reads the files of the record opened by open
+// .cfg and .dat files are read if present, the .cff file otherwise, .hdr and .inf are optional
+func (cfg *CFG) ReadRecord(files *RecordFiles, open func(name string) (io.ReadCloser, error)) error {
+	return cfg.ReadRecordContext(context.Background(), files, open)
+}
+
+// Reads the files of the record like ReadRecord, stopping when ctx is done
+func (cfg *CFG) ReadRecordContext(ctx context.Context, files *RecordFiles, open func(name string) (io.ReadCloser, error)) error {
+	if cfg == nil || files == nil {
+		return errors.New("invalid cfg or record files")
+	}
+	read := func(name string, fn func(rd io.Reader) error) error {
+		file, err := open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return fn(file)
+	}
+	switch {
+	case files.GetCFG() != "" && files.GetDAT() != "":
+		if err := read(files.GetCFG(), func(rd io.Reader) error { return cfg.ReadCFGContext(ctx, rd) }); err != nil {
+			return err
+		}
+		if err := read(files.GetDAT(), func(rd io.Reader) error { return cfg.ReadDATContext(ctx, rd) }); err != nil {
+			return err
+		}
+	case files.GetCFF() != "":
+		if err := read(files.GetCFF(), func(rd io.Reader) error { return cfg.ReadCFFContext(ctx, rd) }); err != nil {
+			return err
+		}
+	case files.GetCFG() != "":
+		return &fs.PathError{Op: "open", Path: files.GetName() + ".dat", Err: fs.ErrNotExist}
+	default:
+		return &fs.PathError{Op: "open", Path: files.GetName() + ".cfg", Err: fs.ErrNotExist}
+	}
+	if files.GetHDR() != "" {
+		if err := read(files.GetHDR(), func(rd io.Reader) error { return cfg.ReadHDR(&contextReader{ctx, rd}) }); err != nil {
+			return err
+		}
+	}
+	if files.GetINF() != "" {
+		err := read(files.GetINF(), func(rd io.Reader) (err error) {
+			cfg.InfoFileContent, err = ioutil.ReadAll(&contextReader{ctx, rd})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open loads the record of which path is any file (.cfg, .dat, .hdr, .inf or .cff)
+// or the path without extension, the other files are found case-insensitively
+func Open(name string) (*CFG, error) {
+	return OpenFS(os.DirFS(filepath.Dir(name)), filepath.Base(name))
+}
+
+// OpenFS loads the record of which name is any file like Open, from the file system fsys
+func OpenFS(fsys fs.FS, name string) (*CFG, error) {
+	dir := path.Dir(name)
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, v := range entries {
+		if !v.IsDir() {
+			names = append(names, v.Name())
+		}
+	}
+
+	stem, _ := splitRecordName(path.Base(name))
+	for _, files := range GroupFiles(names) {
+		if strings.EqualFold(files.Name, stem) {
+			cfg := New()
+			err := cfg.ReadRecord(&files, func(name string) (io.ReadCloser, error) {
+				return fsys.Open(path.Join(dir, name))
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &cfg, nil
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
@@ -0,0 +1,93 @@
+package comgo
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Number of samples decoded by a worker at once
+// a multiple of 64 so no two workers set states in the same word of a Bitset
+const parallelChunk = 64 * 64
+
+// Decodes the whole data file content like Decode, splitting binary data files
+// in chunks of samples decoded by workers goroutines (GOMAXPROCS if workers <= 0)
+// Each worker fills its own range of the columns, the result does not depend on the
+// number of workers. ASCII data files are decoded by Decode
+func (cfg *CFG) DecodeParallel(workers int) (*Record, error) {
+	return cfg.DecodeParallelContext(context.Background(), workers)
+}
+
+// Decodes the whole data file content like DecodeParallel
+// Workers stop between chunks when ctx is done, returning a CancelError
+// with the number of samples of the chunks decoded so far
+func (cfg *CFG) DecodeParallelContext(ctx context.Context, workers int) (*Record, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers == 1 || cfg.dataFileType() == DataFileASCII {
+		return cfg.DecodeContext(ctx)
+	}
+	if err := cfg.guardMapped(cfg.checkData); err != nil {
+		return nil, err
+	}
+
+	num := cfg.GetSamplingNumber()
+	nA, nD := int(cfg.GetAnalogDetail().GetChannelTotal()), int(cfg.GetDigitDetail().GetChannelTotal())
+	rec := newRecord(num, nA, nD)
+	content, NB := cfg.GetDataFileContent(), cfg.recordSize()
+	format, size := cfg.dataFileType(), cfg.analogSize()
+
+	chunks := (num + parallelChunk - 1) / parallelChunk
+	if workers > chunks {
+		workers = chunks
+	}
+	errs := make([]error, chunks)
+	var decoded, skipped int64
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := record{analog: make([]float64, nA), digit: make([]uint8, nD)}
+			for c := range jobs {
+				if ctx.Err() != nil {
+					atomic.AddInt64(&skipped, 1)
+					continue
+				}
+				start, end := c*parallelChunk, (c+1)*parallelChunk
+				if end > num {
+					end = num
+				}
+				// Each goroutine recovers its own faults of a mapped data file
+				errs[c] = cfg.guardMapped(func() error {
+					for i := start; i < end; i++ {
+						r.decodeBinary(content[i*NB:i*NB+NB], format, size)
+						rec.set(i, &r)
+					}
+					return nil
+				})
+				atomic.AddInt64(&decoded, int64(end-start))
+			}
+		}()
+	}
+	for c := 0; c < chunks; c++ {
+		jobs <- c
+	}
+	close(jobs)
+	wg.Wait()
+
+	if skipped > 0 {
+		return nil, &CancelError{Samples: int(decoded), Err: ctx.Err()}
+	}
+
+	// Report the error of the first failed chunk
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rec, nil
+}
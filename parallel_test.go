@@ -0,0 +1,51 @@
+package comgo
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestDecodeParallel(t *testing.T) {
+	for _, name := range testRecords {
+		cfg := readTestRecord(t, name)
+		want, err := cfg.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, workers := range []int{0, 2, 3, 8} {
+			got, err := cfg.DecodeParallel(workers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: DecodeParallel(%d) differs from Decode", name, workers)
+			}
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	cfg := readTestRecord(b, "test1")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := cfg.Decode(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeParallel(b *testing.B) {
+	cfg := readTestRecord(b, "test1")
+	for _, workers := range []int{1, 2, 4, 8, 16, 32} {
+		b.Run(strconv.Itoa(workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				if _, err := cfg.DecodeParallel(workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package comgo
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+)
+
+/*
+ * Record - Columnar content of the data file
+ * @Samples: Sample number of each sample
+ * @Stamps: Time stamp of each sample (0xFFFFFFFF if missing)
+ * @Analog: Raw analog values of each analog channel (NaN if missing)
+ * @Digit: States of each digital channel
+ */
+type Record struct {
+	Samples []uint32
+	Stamps  []uint32
+	Analog  [][]float64
+	Digit   []Bitset
+}
+
+func (m *Record) GetSamples() []uint32 {
+	if m != nil {
+		return m.Samples
+	}
+	return nil
+}
+
+func (m *Record) GetStamps() []uint32 {
+	if m != nil {
+		return m.Stamps
+	}
+	return nil
+}
+
+func (m *Record) GetAnalog() [][]float64 {
+	if m != nil {
+		return m.Analog
+	}
+	return nil
+}
+
+func (m *Record) GetDigit() []Bitset {
+	if m != nil {
+		return m.Digit
+	}
+	return nil
+}
+
+// Return the number of samples of the record
+func (m *Record) Len() int {
+	return len(m.GetSamples())
+}
+
+// Bitset - States of a digital channel, one bit per sample
+type Bitset []uint64
+
+// Return a bitset able to hold n states
+func NewBitset(n int) Bitset {
+	return make(Bitset, (n+63)>>6)
+}
+
+// Return the state (0 or 1) of the i-th sample
+func (b Bitset) Get(i int) uint8 {
+	return uint8(b[i>>6] >> uint(i&63) & 1)
+}
+
+// Set the state of the i-th sample, any non-zero value is 1
+func (b Bitset) Set(i int, state uint8) {
+	if state != 0 {
+		b[i>>6] |= 1 << uint(i&63)
+	} else {
+		b[i>>6] &^= 1 << uint(i&63)
+	}
+}
+
+// Decodes the whole data file content in one pass
+// All channel accessors read from the returned record
+func (cfg *CFG) Decode() (*Record, error) {
+	return cfg.DecodeContext(context.Background())
+}
+
+// Decodes the whole data file content like Decode
+// Decoding stops when ctx is done, returning a CancelError
+func (cfg *CFG) DecodeContext(ctx context.Context) (*Record, error) {
+	// Check before allocating the columns from the configuration
+	if err := cfg.guardMapped(cfg.checkData); err != nil {
+		return nil, err
+	}
+
+	rec := newRecord(cfg.GetSamplingNumber(), int(cfg.GetAnalogDetail().GetChannelTotal()), int(cfg.GetDigitDetail().GetChannelTotal()))
+	err := cfg.guardMapped(func() error {
+		return cfg.scanRecords(func(i int, r *record) error {
+			if err := checkContext(ctx, i); err != nil {
+				return err
+			}
+			rec.set(i, r)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// Return a record of num samples with nA analog and nD digital columns
+func newRecord(num, nA, nD int) *Record {
+	rec := Record{
+		Samples: make([]uint32, num),
+		Stamps:  make([]uint32, num),
+		Analog:  make([][]float64, nA),
+		Digit:   make([]Bitset, nD),
+	}
+	for k := range rec.Analog {
+		rec.Analog[k] = make([]float64, num)
+	}
+	for k := range rec.Digit {
+		rec.Digit[k] = NewBitset(num)
+	}
+	return &rec
+}
+
+// Store the decoded sample r as the i-th sample of the record
+func (m *Record) set(i int, r *record) {
+	m.Samples[i] = r.sample
+	m.Stamps[i] = r.stamp
+	for k, v := range r.analog {
+		m.Analog[k][i] = v
+	}
+	for k, v := range r.digit {
+		m.Digit[k].Set(i, v)
+	}
+}
+
+// Return the record decoded by ReadDAT
+// the data file content is decoded if it has not been yet, and kept for the next calls
+// (not for binary data files mapped by OpenMapped, decoded one channel at a time)
+func (cfg *CFG) GetRecord() (*Record, error) {
+	return cfg.getRecord(context.Background())
+}
+
+func (cfg *CFG) getRecord(ctx context.Context) (*Record, error) {
+	if cfg != nil && cfg.record != nil && sameContent(cfg.recordContent, cfg.DataFileContent) {
+		if err := cfg.record.check(int(cfg.GetAnalogDetail().GetChannelTotal()), int(cfg.GetDigitDetail().GetChannelTotal())); err != nil {
+			return nil, err
+		}
+		return cfg.record, nil
+	}
+	record, err := cfg.DecodeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.mapped == nil || cfg.dataFileType() == DataFileASCII {
+		cfg.setRecord(record)
+	}
+	return record, nil
+}
+
+// Store the record decoded from the current data file content
+func (cfg *CFG) setRecord(record *Record) {
+	cfg.record, cfg.recordContent = record, cfg.DataFileContent
+}
+
+// Check a and b are the same content, not only equal bytes
+func sameContent(a, b []byte) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// Check if channels are decoded one at a time straight from the data file content
+// instead of from a record, only for binary data files mapped by OpenMapped
+func (cfg *CFG) decodeColumns() bool {
+	if cfg == nil || cfg.record != nil || cfg.mapped == nil {
+		return false
+	}
+	switch cfg.dataFileType() {
+	case DataFileBinary, DataFileBinary32, DataFileFloat32:
+		return true
+	}
+	return false
+}
+
+// Return the raw values of the k-th analog channel
+func (cfg *CFG) analogColumn(ctx context.Context, k int) (column []float64, err error) {
+	if !cfg.decodeColumns() {
+		record, err := cfg.getRecord(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return record.GetAnalog()[k], nil
+	}
+	if err := cfg.checkData(); err != nil {
+		return nil, err
+	}
+	content, NB := cfg.GetDataFileContent(), cfg.recordSize()
+	format, size := cfg.dataFileType(), cfg.analogSize()
+	column = make([]float64, cfg.GetSamplingNumber())
+	err = cfg.guardMapped(func() error {
+		for i := range column {
+			if err := checkContext(ctx, i); err != nil {
+				return err
+			}
+			column[i] = binaryAnalog(content[i*NB:], k, format, size)
+		}
+		return nil
+	})
+	return column, err
+}
+
+// Return the states of the k-th digital channel and the number of samples
+func (cfg *CFG) digitColumn(ctx context.Context, k int) (states Bitset, n int, err error) {
+	if !cfg.decodeColumns() {
+		record, err := cfg.getRecord(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		return record.GetDigit()[k], record.Len(), nil
+	}
+	if err := cfg.checkData(); err != nil {
+		return nil, 0, err
+	}
+	content, NB := cfg.GetDataFileContent(), cfg.recordSize()
+	offset := 8 + int(cfg.GetAnalogDetail().GetChannelTotal())*cfg.analogSize()
+	n = cfg.GetSamplingNumber()
+	states = NewBitset(n)
+	err = cfg.guardMapped(func() error {
+		for i := 0; i < n; i++ {
+			if err := checkContext(ctx, i); err != nil {
+				return err
+			}
+			states.Set(i, binaryDigit(content[i*NB+offset:], k))
+		}
+		return nil
+	})
+	return states, n, err
+}
+
+// Return the time stamp of each sample
+func (cfg *CFG) stampColumn(ctx context.Context) (stamps []uint32, err error) {
+	if !cfg.decodeColumns() {
+		record, err := cfg.getRecord(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return record.GetStamps(), nil
+	}
+	if err := cfg.checkData(); err != nil {
+		return nil, err
+	}
+	content, NB := cfg.GetDataFileContent(), cfg.recordSize()
+	stamps = make([]uint32, cfg.GetSamplingNumber())
+	err = cfg.guardMapped(func() error {
+		for i := range stamps {
+			if err := checkContext(ctx, i); err != nil {
+				return err
+			}
+			stamps[i] = binary.LittleEndian.Uint32(content[i*NB+4:])
+		}
+		return nil
+	})
+	return stamps, err
+}
+
+// Check the record has nA analog and nD digital columns of the same length
+func (m *Record) check(nA, nD int) error {
+	num := m.Len()
+	if len(m.GetStamps()) != num {
+		return errors.New("invalid record: number of stamps does not match number of samples")
+	}
+	if len(m.GetAnalog()) != nA || len(m.GetDigit()) != nD {
+		return errors.New("invalid record: number of columns does not match number of channels")
+	}
+	for _, column := range m.GetAnalog() {
+		if len(column) != num {
+			return errors.New("invalid record: analog column length does not match number of samples")
+		}
+	}
+	for _, states := range m.GetDigit() {
+		if len(states) < (num+63)>>6 {
+			return errors.New("invalid record: digital column length does not match number of samples")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package comgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Records of examples/data
+var testRecords = []string{"test1", "test2"}
+
+// Reads the .cfg file of the record name of examples/data
+func readTestCFG(tb testing.TB, name string) *CFG {
+	tb.Helper()
+	file, err := os.Open("examples/data/" + name + ".cfg")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer file.Close()
+	cfg := New()
+	if err := cfg.ReadCFG(file); err != nil {
+		tb.Fatal(err)
+	}
+	return &cfg
+}
+
+// Reads the .dat file of the record name of examples/data
+func readTestDAT(tb testing.TB, name string) []byte {
+	tb.Helper()
+	content, err := os.ReadFile("examples/data/" + name + ".dat")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return content
+}
+
+// Loads the record name of examples/data
+func readTestRecord(tb testing.TB, name string) *CFG {
+	tb.Helper()
+	cfg := readTestCFG(tb, name)
+	if err := cfg.ReadDAT(bytes.NewReader(readTestDAT(tb, name))); err != nil {
+		tb.Fatal(err)
+	}
+	return cfg
+}
+
+// Decodes analog channel num by rescanning every sample of the binary data file,
+// the way GetAnalogChannelData did before the data file was decoded into a Record
+func rescanAnalogChannel(cfg *CFG, num uint16) (result []float64, err error) {
+	analogDetail := cfg.GetAnalogDetail()
+	NB := cfg.recordSize()
+	factor := analogDetail.GetConversionFactors()
+	content := cfg.GetDataFileContent()
+	for i := 0; i < cfg.GetSamplingNumber(); i++ {
+		s := content[i*NB : i*NB+NB]
+
+		var data struct {
+			Sample int32
+			Stamp  int32
+		}
+		value := make([]int16, (NB-8)/2)
+		if err := binary.Read(bytes.NewReader(s[:8]), binary.LittleEndian, &data); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(bytes.NewReader(s[8:]), binary.LittleEndian, &value); err != nil {
+			return nil, err
+		}
+		result = append(result, float64(value[num-1])*factor["a"][num-1]+factor["b"][num-1])
+	}
+	return result, nil
+}
+
+func TestDecodeMatchesRescan(t *testing.T) {
+	for _, name := range testRecords {
+		cfg := readTestRecord(t, name)
+		for num := uint16(1); num <= cfg.GetAnalogDetail().GetChannelTotal(); num++ {
+			want, err := rescanAnalogChannel(cfg, num)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := cfg.GetAnalogChannelData(num)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("%s channel %d: %d samples, want %d", name, num, len(got), len(want))
+			}
+			// Missing samples (0x8000) were scaled as real values
+			factor := cfg.GetAnalogDetail().GetConversionFactors()
+			missing := -32768*factor["a"][num-1] + factor["b"][num-1]
+			for i := range want {
+				if got[i] != want[i] && !(math.IsNaN(got[i]) && want[i] == missing) {
+					t.Fatalf("%s channel %d sample %d: %g, want %g", name, num, i, got[i], want[i])
+				}
+			}
+		}
+	}
+}
+
+func TestGetRecordKeepsDecodedRecord(t *testing.T) {
+	// Data file content set directly, not decoded by ReadDAT
+	cfg := readTestCFG(t, "test1")
+	cfg.DataFileContent = readTestDAT(t, "test1")
+	first, err := cfg.GetRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second, err := cfg.GetRecord(); err != nil || second != first {
+		t.Fatalf("record decoded again: %v", err)
+	}
+
+	// New content is decoded again
+	cfg.DataFileContent = append([]byte(nil), cfg.DataFileContent...)
+	if third, err := cfg.GetRecord(); err != nil || third == first {
+		t.Fatalf("record of previous content returned: %v", err)
+	}
+}
+
+func TestReadCFGAgain(t *testing.T) {
+	// test1 written as a 2013 configuration with time code and time quality
+	first := readTestCFG(t, "test1")
+	first.RevisionYear, first.TimeCode, first.LocalCode, first.TimeQuality, first.LeapSecond = 2013, "-5h30", "x", 0xA, 1
+	var buf bytes.Buffer
+	if err := first.WriteCFG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	test2, err := os.ReadFile("examples/data/test2.cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := New()
+	for _, content := range [][]byte{buf.Bytes(), test2} {
+		if err := cfg.ReadCFG(bytes.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := readTestCFG(t, "test2")
+	if len(cfg.GetSampleDetail()) != len(want.GetSampleDetail()) || cfg.GetSamplingNumber() != want.GetSamplingNumber() {
+		t.Errorf("sample detail %v, want %v", cfg.GetSampleDetail(), want.GetSampleDetail())
+	}
+	if cfg.GetRevisionYear() != want.GetRevisionYear() || cfg.GetTimeCode() != "" || cfg.GetLocalCode() != "" ||
+		cfg.GetTimeQuality() != 0 || cfg.GetLeapSecond() != 0 {
+		t.Errorf("revision %d, time code %q, local code %q, time quality %d and leap second %d kept from the previous read",
+			cfg.GetRevisionYear(), cfg.GetTimeCode(), cfg.GetLocalCode(), cfg.GetTimeQuality(), cfg.GetLeapSecond())
+	}
+	if !cfg.GetStartTime().Equal(want.GetStartTime()) {
+		t.Errorf("start time %v, want %v", cfg.GetStartTime(), want.GetStartTime())
+	}
+}
+
+func TestDecodeASCIIBoundsSamples(t *testing.T) {
+	// 100000 samples of 1000 analog channels announced, only blank lines
+	cfg := New()
+	cfg.AnalogDetail = &ChannelA{ChannelTotal: 1000}
+	cfg.DigitDetail = &ChannelD{}
+	cfg.SampleRateNum = 1
+	cfg.SampleDetail = []SampleRate{{Rate: 1000, Number: 100000}}
+	cfg.DataFileType = DataFileASCII
+	cfg.DataFileContent = bytes.Repeat([]byte("\n"), 100000)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := cfg.Decode()
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, ErrTruncated) {
+		t.Fatalf("got %v, want ErrTruncated", err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("%d bytes allocated before the data file was found truncated", allocated)
+	}
+}
+
+func TestTimeSource(t *testing.T) {
+	// Time stamps of test2 wrap at 65536 microseconds
+	cfg := readTestRecord(t, "test2")
+	stamps, err := cfg.stampColumn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, source := range []string{"", TimeSourceStamp, TimeSourceRate} {
+		cfg.TimeSource = source
+		offsets, err := cfg.GetTimeOffsets()
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsetAt, err := cfg.sampleOffsetFunc(bytes.NewReader(cfg.GetDataFileContent()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range offsets {
+			want := time.Duration(stamps[i]) * time.Microsecond
+			if source == TimeSourceRate {
+				want = cfg.sampleOffset(i)
+			}
+			if v != want {
+				t.Fatalf("time source %q sample %d: %v, want %v", source, i, v, want)
+			}
+			if got, err := offsetAt(i); err != nil || got != v {
+				t.Fatalf("time source %q sample %d: %v read from data file, want %v: %v", source, i, got, v, err)
+			}
+		}
+	}
+
+	// Sampling rates used without time multiplication factor
+	cfg.TimeSource, cfg.TimeFactor = TimeSourceStamp, 0
+	offsets, err := cfg.GetTimeOffsets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := len(offsets) - 1; offsets[last] != cfg.sampleOffset(last) {
+		t.Errorf("sample %d: %v, want %v", last, offsets[last], cfg.sampleOffset(last))
+	}
+}
+
+// Loads test1 and reads every analog channel, rescanning the data file for each channel
+func BenchmarkAnalogChannelsRescan(b *testing.B) {
+	cfg := readTestCFG(b, "test1")
+	content := readTestDAT(b, "test1")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c := *cfg
+		c.DataFileContent = content
+		for num := uint16(1); num <= c.GetAnalogDetail().GetChannelTotal(); num++ {
+			if _, err := rescanAnalogChannel(&c, num); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// Loads test1 and reads every analog channel from the record decoded once by ReadDAT
+func BenchmarkAnalogChannelsRecord(b *testing.B) {
+	cfg := readTestCFG(b, "test1")
+	content := readTestDAT(b, "test1")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c := *cfg
+		if err := c.ReadDAT(bytes.NewReader(content)); err != nil {
+			b.Fatal(err)
+		}
+		for num := uint16(1); num <= c.GetAnalogDetail().GetChannelTotal(); num++ {
+			if _, err := c.GetAnalogChannelData(num); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
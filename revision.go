@@ -0,0 +1,62 @@
+package comgo
+
+import (
+	"bytes"
+	"time"
+)
+
+// Revisions of IEEE C37.111 whose layout rules are applied
+const (
+	Revision1991 = 1991
+	Revision1999 = 1999
+	Revision2013 = 2013
+)
+
+// Date and time layouts of each revision, fractional seconds of any
+// length are accepted after the seconds when parsing
+const (
+	TimeFormat1991 = "01/02/06T15:04:05"
+	TimeFormat1999 = "02/01/2006T15:04:05"
+)
+
+// Return the revision whose rules apply to the revision year of .cfg file
+// no revision year is the 1991 revision, years between two revisions use the older one
+func revisionRules(year uint16) uint16 {
+	switch {
+	case year == 0 || year < Revision1999:
+		return Revision1991
+	case year < Revision2013:
+		return Revision1999
+	default:
+		return Revision2013
+	}
+}
+
+// Return the revision whose layout rules have been applied by ReadCFG
+func (cfg *CFG) GetRevisionRules() uint16 {
+	return revisionRules(cfg.GetRevisionYear())
+}
+
+// Number of fields of analog and digital channel lines of the revision
+func channelFields(rules uint16) (analog, digit int) {
+	if rules == Revision1991 {
+		return 10, 3
+	}
+	return 13, 5
+}
+
+// Parses date and time of .cfg file ([dd,mm,yyyy,hh,mm,ss.ssssss])
+// 1991: mm/dd/yy, 2013: nanosecond resolution is allowed
+// nano reports if the time has more than 6 fractional digits
+func parseCFGTime(field []byte, rules uint16) (t time.Time, nano bool, err error) {
+	text := ByteToString(bytes.Replace(field, []byte(","), []byte("T"), 1))
+	if k := bytes.LastIndexByte([]byte(text), '.'); k >= 0 {
+		nano = len(text)-k-1 > 6
+	}
+	if rules == Revision1991 {
+		t, err = time.Parse(TimeFormat1991, text)
+	} else {
+		t, err = time.Parse(TimeFormat1999, text)
+	}
+	return t, nano, err
+}
@@ -0,0 +1,97 @@
+package comgo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Configuration of the 1991 revision: no revision year, 10 analog and 3 digital fields,
+// mm/dd/yy dates and no time multiplication factor
+const testCFG1991 = `STATION 1991,RECORDER
+3,2A,1D
+1,IA,A,,A,0.1,0,0,-32767,32767
+2,VA,A,,kV,0.5,1,0,-32767,32767
+1,TRIP,0
+50
+1
+1000,3
+03/31/17,22:01:11.125094
+03/31/17,22:01:11.126094
+ASCII
+`
+
+func TestParseCFGTime(t *testing.T) {
+	tests := []struct {
+		text  string
+		rules uint16
+		want  time.Time
+		nano  bool
+		ok    bool
+	}{
+		{"03/31/17,22:01:11.125094", Revision1991, time.Date(2017, 3, 31, 22, 1, 11, 125094000, time.UTC), false, true},
+		{"12/01/91,00:00:00", Revision1991, time.Date(1991, 12, 1, 0, 0, 0, 0, time.UTC), false, true},
+		{"31/03/2017,22:01:11.125094", Revision1999, time.Date(2017, 3, 31, 22, 1, 11, 125094000, time.UTC), false, true},
+		{"31/03/2017,22:01:11.125094123", Revision2013, time.Date(2017, 3, 31, 22, 1, 11, 125094123, time.UTC), true, true},
+		{" 01/12/1991,00:00:00.5 ", Revision1999, time.Date(1991, 12, 1, 0, 0, 0, 500000000, time.UTC), false, true},
+		{"31/03/2017,22:01:11.125094", Revision1991, time.Time{}, false, false},
+		{"03/31/17,22:01:11.125094", Revision1999, time.Time{}, false, false},
+		{"31/03/2017", Revision1999, time.Time{}, false, false},
+	}
+	for _, tt := range tests {
+		got, nano, err := parseCFGTime([]byte(tt.text), tt.rules)
+		if !tt.ok {
+			if err == nil {
+				t.Errorf("%q (%d): got %v, want an error", tt.text, tt.rules, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q (%d): %v", tt.text, tt.rules, err)
+			continue
+		}
+		if !got.Equal(tt.want) || nano != tt.nano {
+			t.Errorf("%q (%d): got %v, nano %v, want %v, nano %v", tt.text, tt.rules, got, nano, tt.want, tt.nano)
+		}
+	}
+}
+
+func TestReadCFG1991(t *testing.T) {
+	cfg := New()
+	if err := cfg.ReadCFG(strings.NewReader(testCFG1991)); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GetRevisionRules() != Revision1991 || cfg.GetTimeFactor() != 1 {
+		t.Errorf("revision rules %d, time factor %g", cfg.GetRevisionRules(), cfg.GetTimeFactor())
+	}
+	if want := time.Date(2017, 3, 31, 22, 1, 11, 125094000, time.UTC); !cfg.GetStartTime().Equal(want) {
+		t.Errorf("start time %v, want %v", cfg.GetStartTime(), want)
+	}
+	if names := cfg.GetAnalogChannelNames(); len(names) != 2 || names[1] != "VA" {
+		t.Errorf("analog channels %q", names)
+	}
+	if err := cfg.ReadDAT(strings.NewReader("1,0,10,20,1\n2,1000,11,21,0\n3,2000,12,22,1\n")); err != nil {
+		t.Fatal(err)
+	}
+	values, err := cfg.GetAnalogChannelData(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0] != 11 || values[2] != 12 {
+		t.Errorf("analog channel 2 %v, want [11 11.5 12]", values)
+	}
+
+	// Written back with the layout of the 1991 revision
+	var buf bytes.Buffer
+	if err := cfg.WriteCFG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read := New()
+	if err := read.ReadCFG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if read.GetRevisionRules() != Revision1991 || !read.GetTriggerTime().Equal(cfg.GetTriggerTime()) {
+		t.Errorf("revision rules %d, trigger time %v, want %v", read.GetRevisionRules(), read.GetTriggerTime(), cfg.GetTriggerTime())
+	}
+}
@@ -0,0 +1,223 @@
+package comgo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+)
+
+/*
+ * Sample - One sample of the data file
+ * @Number: Sample number
+ * @Stamp: Time stamp (0xFFFFFFFF if missing)
+ * @Raw: Raw analog values of each analog channel (NaN if missing)
+ * @Analog: Analog values of each analog channel converted with factors a and b (NaN if missing)
+ * @Digit: States (0 or 1) of each digital channel
+ */
+type Sample struct {
+	Number uint32
+	Stamp  uint32
+	Raw    []float64
+	Analog []float64
+	Digit  []uint8
+}
+
+func (m *Sample) GetNumber() uint32 {
+	if m != nil {
+		return m.Number
+	}
+	return 0
+}
+
+func (m *Sample) GetStamp() uint32 {
+	if m != nil {
+		return m.Stamp
+	}
+	return 0
+}
+
+func (m *Sample) GetRaw() []float64 {
+	if m != nil {
+		return m.Raw
+	}
+	return nil
+}
+
+func (m *Sample) GetAnalog() []float64 {
+	if m != nil {
+		return m.Analog
+	}
+	return nil
+}
+
+func (m *Sample) GetDigit() []uint8 {
+	if m != nil {
+		return m.Digit
+	}
+	return nil
+}
+
+/*
+ * SampleReader - Reads the samples of a data file one at a time
+ * @rd: Buffered data file
+ * @ctx: Context stopping Next once done, may be nil
+ * @format: Data file type
+ * @size: Number of bytes of each analog value (binary)
+ * @num: Number of samples of the configuration
+ * @a: Conversion factor a of each analog channel
+ * @b: Conversion factor b of each analog channel
+ * @i: Index of the next sample
+ * @offset: Byte offset of the next sample
+ * @buf: Bytes of the current sample or line
+ * @r: Current decoded record
+ * @sample: Current sample, reused between calls of Next
+ * @err: Sticky error
+ */
+type SampleReader struct {
+	rd     *bufio.Reader
+	ctx    context.Context
+	format string
+	size   int
+	num    int
+	a, b   []float64
+	i      int
+	offset int
+	buf    []byte
+	r      record
+	sample Sample
+	err    error
+}
+
+// Return a reader decoding the samples of the data file rd one at a time
+// The data file is never held in memory, only one sample is
+func (cfg *CFG) NewSampleReader(rd io.Reader) (*SampleReader, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cfg file, read .cfg first")
+	}
+	analogDetail, digitDetail := cfg.GetAnalogDetail(), cfg.GetDigitDetail()
+	if analogDetail == nil {
+		return nil, errors.New("invalid analog channel")
+	}
+	if digitDetail == nil {
+		return nil, errors.New("invalid digital channel")
+	}
+	if len(cfg.GetSampleDetail()) == 0 || cfg.GetSamplingNumber() < 0 {
+		return nil, errors.New("invalid or not enough sample detail")
+	}
+	nA, nD := int(analogDetail.GetChannelTotal()), int(digitDetail.GetChannelTotal())
+	factor := analogDetail.GetConversionFactors()
+	if len(factor["a"]) < nA || len(factor["b"]) < nA {
+		return nil, errors.New("invalid conversion factors")
+	}
+
+	sr := SampleReader{
+		rd:     bufio.NewReaderSize(rd, 64<<10),
+		format: cfg.dataFileType(),
+		size:   cfg.analogSize(),
+		num:    cfg.GetSamplingNumber(),
+		a:      factor["a"][:nA],
+		b:      factor["b"][:nA],
+	}
+	switch sr.format {
+	case DataFileASCII:
+	case DataFileBinary, DataFileBinary32, DataFileFloat32:
+		sr.buf = make([]byte, cfg.recordSize())
+	default:
+		return nil, errors.New("unsupported data file type: " + cfg.GetDataFileType())
+	}
+	sr.r = record{analog: make([]float64, nA), digit: make([]uint8, nD)}
+	sr.sample = Sample{Raw: sr.r.analog, Analog: make([]float64, nA), Digit: sr.r.digit}
+	return &sr, nil
+}
+
+// Return the next sample, io.EOF after the last sample of the configuration
+// The returned sample is overwritten by the next call, copy it to keep it
+func (sr *SampleReader) Next() (*Sample, error) {
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	if sr.i >= sr.num {
+		sr.err = io.EOF
+		return nil, sr.err
+	}
+	if sr.ctx != nil {
+		if err := checkContext(sr.ctx, sr.i); err != nil {
+			sr.err = err
+			return nil, err
+		}
+	}
+
+	var err error
+	if sr.format == DataFileASCII {
+		err = sr.nextASCII()
+	} else {
+		err = sr.nextBinary()
+	}
+	if err != nil {
+		// The data file read by contextReader failed because ctx is done
+		if sr.ctx != nil && sr.ctx.Err() != nil {
+			err = &CancelError{Samples: sr.i, Err: sr.ctx.Err()}
+		}
+		sr.err = err
+		return nil, err
+	}
+
+	sr.sample.Number, sr.sample.Stamp = sr.r.sample, sr.r.stamp
+	for k, v := range sr.r.analog {
+		sr.sample.Analog[k] = v*sr.a[k] + sr.b[k]
+	}
+	sr.i++
+	return &sr.sample, nil
+}
+
+// Return the number of samples read so far
+func (sr *SampleReader) Count() int {
+	return sr.i
+}
+
+// Reads and decodes one sample of binary data file
+func (sr *SampleReader) nextBinary() error {
+	if n, err := io.ReadFull(sr.rd, sr.buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return datError(sr.i, sr.offset+n, -1, "", nil, ErrTruncated)
+		}
+		return err
+	}
+	sr.r.decodeBinary(sr.buf, sr.format, sr.size)
+	sr.offset += len(sr.buf)
+	return nil
+}
+
+// Reads and decodes the next non blank line of ASCII data file
+func (sr *SampleReader) nextASCII() error {
+	for {
+		start := sr.offset
+		line, err := sr.readLine()
+		sr.offset += len(line)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		line = bytes.TrimSpace(line)
+		// Skip blank lines and the end of file marker (0x1A)
+		if len(line) > 0 && !(len(line) == 1 && line[0] == 0x1A) {
+			return sr.r.decodeASCII(line, sr.i, start)
+		}
+		if err == io.EOF {
+			return datError(sr.i, sr.offset, -1, "", nil, ErrTruncated)
+		}
+	}
+}
+
+// Reads one line including its line feed into the reused buffer
+func (sr *SampleReader) readLine() ([]byte, error) {
+	sr.buf = sr.buf[:0]
+	for {
+		chunk, err := sr.rd.ReadSlice('\n')
+		sr.buf = append(sr.buf, chunk...)
+		if err != bufio.ErrBufferFull {
+			return sr.buf, err
+		}
+	}
+}
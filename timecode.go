@@ -0,0 +1,136 @@
+package comgo
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Leap second indicators (leapsec) of C37.111-2013
+const (
+	LeapSecondNone        = 0 // No leap second in the record
+	LeapSecondAdded       = 1 // Leap second added in the record
+	LeapSecondSubtracted  = 2 // Leap second subtracted in the record
+	LeapSecondUnsupported = 3 // Time source does not have the capability to address leap seconds
+)
+
+// Time quality codes (tmq_code) of C37.111-2013, as in IEEE C37.118
+const (
+	TimeQualityLocked  = 0x0 // Clock in locked condition, normal operation
+	TimeQualityFailure = 0xF // Clock failure, time not reliable
+)
+
+// Maximum time error of each time quality code, codes not listed are reserved
+var timeQualityErrors = map[uint8]time.Duration{
+	0x0: 0,
+	0x1: time.Nanosecond,
+	0x2: 10 * time.Nanosecond,
+	0x3: 100 * time.Nanosecond,
+	0x4: time.Microsecond,
+	0x5: 10 * time.Microsecond,
+	0x6: 100 * time.Microsecond,
+	0x7: time.Millisecond,
+	0x8: 10 * time.Millisecond,
+	0x9: 100 * time.Millisecond,
+	0xA: time.Second,
+	0xB: 10 * time.Second,
+}
+
+// Return the time zone of StartTime and TriggerTime given by time_code
+// return nil if time_code is not present
+func (cfg *CFG) GetTimeZone() *time.Location {
+	loc, err := parseTimeCode(cfg.GetTimeCode())
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// Return the local time zone of the recorder given by local_code
+// return nil if local_code is not present or not used (x)
+func (cfg *CFG) GetLocalZone() *time.Location {
+	loc, err := parseTimeCode(cfg.GetLocalCode())
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// Return StartTime in the local time zone of the recorder
+// StartTime is returned as is if local_code is not present
+func (cfg *CFG) GetLocalStartTime() time.Time {
+	if loc := cfg.GetLocalZone(); loc != nil {
+		return cfg.GetStartTime().In(loc)
+	}
+	return cfg.GetStartTime()
+}
+
+// Return TriggerTime in the local time zone of the recorder
+// TriggerTime is returned as is if local_code is not present
+func (cfg *CFG) GetLocalTriggerTime() time.Time {
+	if loc := cfg.GetLocalZone(); loc != nil {
+		return cfg.GetTriggerTime().In(loc)
+	}
+	return cfg.GetTriggerTime()
+}
+
+// Return the maximum time error indicated by tmq_code
+// ok is false if the clock failed or the code is reserved
+func (cfg *CFG) GetTimeQualityError() (d time.Duration, ok bool) {
+	d, ok = timeQualityErrors[cfg.GetTimeQuality()]
+	return d, ok
+}
+
+// Check if the record contains a leap second
+func (cfg *CFG) HasLeapSecond() bool {
+	leap := cfg.GetLeapSecond()
+	return leap == LeapSecondAdded || leap == LeapSecondSubtracted
+}
+
+// Parses time_code and local_code: [+|-]h[h][hmm], for example -5h30 or +10
+// return an error for empty or not used (x) code
+func parseTimeCode(code string) (*time.Location, error) {
+	code = strings.TrimSpace(code)
+	if code == "" || strings.EqualFold(code, "x") {
+		return nil, errors.New("time code not used")
+	}
+
+	sign, text := 1, code
+	switch text[0] {
+	case '-':
+		sign, text = -1, text[1:]
+	case '+':
+		text = text[1:]
+	}
+
+	hours, minutes := text, ""
+	if k := strings.IndexAny(text, "hH"); k >= 0 {
+		hours, minutes = text[:k], text[k+1:]
+	}
+
+	offset := 0
+	if h, err := strconv.Atoi(hours); err != nil || h < 0 || h > 24 {
+		return nil, errors.New("invalid time code: " + code)
+	} else {
+		offset = h * 3600
+	}
+	if minutes != "" {
+		if m, err := strconv.Atoi(minutes); err != nil || m < 0 || m > 59 {
+			return nil, errors.New("invalid time code: " + code)
+		} else {
+			offset += m * 60
+		}
+	}
+	offset *= sign
+
+	if offset == 0 {
+		return time.UTC, nil
+	}
+	return time.FixedZone("UTC"+code, offset), nil
+}
+
+// Return t with the same wall clock in time zone loc
+func inZone(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
@@ -0,0 +1,91 @@
+package comgo
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestParseTimeCode(t *testing.T) {
+	tests := []struct {
+		code   string
+		offset int
+		ok     bool
+	}{
+		{"-5h30", -(5*3600 + 30*60), true},
+		{"+10", 10 * 3600, true},
+		{"10", 10 * 3600, true},
+		{"+5H45", 5*3600 + 45*60, true},
+		{" -3 ", -3 * 3600, true},
+		{"0", 0, true},
+		{"-0", 0, true},
+		{"x", 0, false},
+		{"X", 0, false},
+		{"", 0, false},
+		{"+25", 0, false},
+		{"5h60", 0, false},
+		{"5:30", 0, false},
+		{"UTC", 0, false},
+	}
+	for _, tt := range tests {
+		loc, err := parseTimeCode(tt.code)
+		if !tt.ok {
+			if err == nil {
+				t.Errorf("%q: got %v, want an error", tt.code, loc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.code, err)
+			continue
+		}
+		if _, offset := time.Date(2013, 1, 1, 0, 0, 0, 0, loc).Zone(); offset != tt.offset {
+			t.Errorf("%q: offset %ds, want %ds", tt.code, offset, tt.offset)
+		}
+	}
+}
+
+func TestReadCFGTimeCode(t *testing.T) {
+	tests := []struct {
+		timeCode  string
+		localCode string
+		offset    int
+		local     bool
+		ok        bool
+	}{
+		{"-5h30", "x", -(5*3600 + 30*60), false, true},
+		{"+10", "+10", 10 * 3600, true, true},
+		{"0", "-5h30", 0, true, true},
+		{"x", "x", 0, false, false},
+		{"+10", "10h99", 0, false, false},
+	}
+	for _, tt := range tests {
+		cfg := readTestCFG(t, "test1")
+		cfg.RevisionYear, cfg.TimeCode, cfg.LocalCode = 2013, tt.timeCode, tt.localCode
+		var buf bytes.Buffer
+		if err := cfg.WriteCFG(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		read := New()
+		err := read.ReadCFG(&buf)
+		if !tt.ok {
+			if err == nil {
+				t.Errorf("%q,%q: read without error", tt.timeCode, tt.localCode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q,%q: %v", tt.timeCode, tt.localCode, err)
+			continue
+		}
+		// The wall clock of the start time is kept in the time zone of time_code
+		start := read.GetStartTime()
+		if _, offset := start.Zone(); offset != tt.offset || start.Hour() != cfg.GetStartTime().Hour() {
+			t.Errorf("%q: start time %v", tt.timeCode, start)
+		}
+		if (read.GetLocalZone() != nil) != tt.local {
+			t.Errorf("%q: local zone %v", tt.localCode, read.GetLocalZone())
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package comgo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Severity of a validation finding
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+/*
+ * Finding - One C37.111 conformance finding
+ * @Severity: Severity of the finding
+ * @Rule: Short identifier of the rule, e.g. "channel-count"
+ * @Message: Description of the finding
+ */
+type Finding struct {
+	Severity Severity
+	Rule     string
+	Message  string
+}
+
+func (m *Finding) GetSeverity() Severity {
+	if m != nil {
+		return m.Severity
+	}
+	return SeverityInfo
+}
+
+func (m *Finding) GetRule() string {
+	if m != nil {
+		return m.Rule
+	}
+	return ""
+}
+
+func (m *Finding) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m Finding) String() string {
+	return m.Severity.String() + ": " + m.Rule + ": " + m.Message
+}
+
+// Findings - Conformance report returned by Validate
+type Findings []Finding
+
+// Check if the report contains a finding of error severity
+func (f Findings) HasErrors() bool {
+	for _, v := range f {
+		if v.Severity >= SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Return an error listing the findings of error severity, nil if there is none
+func (f Findings) Err() error {
+	var messages []string
+	for _, v := range f {
+		if v.Severity >= SeverityError {
+			messages = append(messages, v.Rule+": "+v.Message)
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return errors.New("comtrade validation failed: " + strings.Join(messages, "; "))
+}
+
+// Field length limits of C37.111
+const (
+	maxNameLength    = 64 // station_name, rec_dev_id, ch_id, ccbm
+	maxPhaseLength   = 2  // ph
+	maxUnitLength    = 32 // uu
+	asciiValueLimit  = 99999
+	binaryValueLimit = 32767
+)
+
+// Checks the record against the rules of C37.111 and returns every finding
+// The configuration is checked, and the data file if it has been read
+func (cfg *CFG) Validate() (findings Findings) {
+	add := func(severity Severity, rule, format string, args ...interface{}) {
+		findings = append(findings, Finding{severity, rule, fmt.Sprintf(format, args...)})
+	}
+
+	if cfg == nil || cfg.GetAnalogDetail() == nil || cfg.GetDigitDetail() == nil {
+		add(SeverityError, "cfg", "configuration has not been read")
+		return findings
+	}
+	analogDetail, digitDetail := cfg.GetAnalogDetail(), cfg.GetDigitDetail()
+	nA, nD := int(analogDetail.GetChannelTotal()), int(digitDetail.GetChannelTotal())
+
+	// Revision year
+	switch cfg.GetRevisionYear() {
+	case 0, Revision1991, Revision1999, Revision2013:
+	default:
+		add(SeverityWarning, "revision-year", "unknown revision year %d, %d rules applied", cfg.GetRevisionYear(), cfg.GetRevisionRules())
+	}
+
+	// Station and device identifiers
+	checkLength := func(rule, name, value string, limit int) {
+		if utf8.RuneCountInString(value) > limit {
+			add(SeverityWarning, rule, "%s %q longer than %d characters", name, value, limit)
+		}
+	}
+	checkLength("field-length", "station name", cfg.GetStationName(), maxNameLength)
+	checkLength("field-length", "recording device id", cfg.GetRecordDeviceId(), maxNameLength)
+
+	// Channel counts: TT = ##A + ##D
+	if int(cfg.GetChannelNumber()) != nA+nD {
+		add(SeverityError, "channel-count", "total channel number %d is not %dA + %dD", cfg.GetChannelNumber(), nA, nD)
+	}
+
+	// Analog channels
+	factor := analogDetail.GetConversionFactors()
+	for i := 0; i < nA; i++ {
+		name := fmt.Sprintf("analog channel %d", i+1)
+		if num := channelNumber(analogDetail.GetChannelNumber(), i); int(num) != i+1 {
+			add(SeverityError, "channel-sequence", "%s has number %d", name, num)
+		}
+		checkLength("field-length", name+" id", stringAt(analogDetail.GetChannelNames(), i), maxNameLength)
+		checkLength("field-length", name+" phase", stringAt(analogDetail.GetChannelPhases(), i), maxPhaseLength)
+		checkLength("field-length", name+" circuit component", stringAt(analogDetail.GetChannelElements(), i), maxNameLength)
+		checkLength("field-length", name+" unit", stringAt(analogDetail.GetChannelUnits(), i), maxUnitLength)
+		if i >= len(factor["a"]) || i >= len(factor["b"]) {
+			add(SeverityError, "conversion-factor", "%s has no conversion factors", name)
+		} else if factor["a"][i] == 0 {
+			add(SeverityWarning, "conversion-factor", "%s has conversion factor a = 0", name)
+		}
+		if i >= len(analogDetail.GetValueMin()) || i >= len(analogDetail.GetValueMax()) {
+			add(SeverityError, "value-range", "%s has no min and max values", name)
+			continue
+		}
+		if min, max, _ := analogDetail.valueLimits(i); min > max {
+			add(SeverityError, "value-range", "%s min %g greater than max %g", name, min, max)
+		}
+		min, max := analogDetail.GetValueMin()[i], analogDetail.GetValueMax()[i]
+		switch cfg.dataFileType() {
+		case DataFileASCII:
+			if min < -asciiValueLimit || max > asciiValueLimit {
+				add(SeverityWarning, "value-range", "%s range [%d, %d] exceeds ASCII limits", name, min, max)
+			}
+		case DataFileBinary:
+			if min < -binaryValueLimit-1 || max > binaryValueLimit {
+				add(SeverityError, "value-range", "%s range [%d, %d] exceeds BINARY limits", name, min, max)
+			}
+		}
+		if cfg.GetRevisionRules() != Revision1991 {
+			if ps := stringAt(analogDetail.GetPS(), i); ps != ValuePrimary && ps != ValueSecondary {
+				add(SeverityError, "ps", "%s PS identifier %q is not P or S", name, ps)
+			}
+			if floatAt(analogDetail.GetPrimary(), i, 0) == 0 || floatAt(analogDetail.GetSecondary(), i, 0) == 0 {
+				add(SeverityWarning, "ratio", "%s has zero primary or secondary ratio", name)
+			}
+		}
+	}
+
+	// Digital channels
+	for i := 0; i < nD; i++ {
+		name := fmt.Sprintf("digital channel %d", i+1)
+		if num := channelNumber(digitDetail.GetChannelNumber(), i); int(num) != i+1 {
+			add(SeverityError, "channel-sequence", "%s has number %d", name, num)
+		}
+		checkLength("field-length", name+" id", stringAt(digitDetail.GetChannelNames(), i), maxNameLength)
+		checkLength("field-length", name+" phase", stringAt(digitDetail.GetChannelPhases(), i), maxPhaseLength)
+		checkLength("field-length", name+" circuit component", stringAt(digitDetail.GetChannelElements(), i), maxNameLength)
+		if state := uint8At(digitDetail.GetInitialState(), i); state > 1 {
+			add(SeverityError, "digital-state", "%s normal state %d is not 0 or 1", name, state)
+		}
+	}
+
+	// Sampling rates
+	sampleDetail := cfg.GetSampleDetail()
+	if n := int(cfg.GetSampleRateNum()); n != len(sampleDetail) && !(n == 0 && len(sampleDetail) == 1) {
+		add(SeverityError, "sample-rate", "nrates %d does not match %d sampling rate lines", n, len(sampleDetail))
+	}
+	if len(sampleDetail) == 0 {
+		add(SeverityError, "sample-rate", "no sampling rate")
+	}
+	last := 0
+	for k, v := range sampleDetail {
+		if cfg.GetSampleRateNum() > 0 && v.GetRate() <= 0 {
+			add(SeverityError, "sample-rate", "sampling rate %d is %g", k+1, v.GetRate())
+		}
+		if v.GetNumber() <= last {
+			add(SeverityError, "endsamp", "endsamp %d of sampling rate %d is not greater than %d", v.GetNumber(), k+1, last)
+		}
+		last = v.GetNumber()
+	}
+
+	// Dates, data file type and time factor
+	if cfg.GetTriggerTime().Before(cfg.GetStartTime()) {
+		add(SeverityWarning, "trigger-time", "trigger time %v before start time %v", cfg.GetTriggerTime(), cfg.GetStartTime())
+	}
+	switch cfg.dataFileType() {
+	case DataFileASCII, DataFileBinary:
+	case DataFileBinary32, DataFileFloat32:
+		if cfg.GetRevisionRules() != Revision2013 {
+			add(SeverityWarning, "data-file-type", "%s data file requires the 2013 revision", cfg.dataFileType())
+		}
+	default:
+		add(SeverityError, "data-file-type", "unknown data file type %q", cfg.GetDataFileType())
+	}
+	if cfg.GetTimeFactor() <= 0 {
+		add(SeverityError, "time-factor", "time multiplication factor %g is not positive", cfg.GetTimeFactor())
+	}
+
+	if len(cfg.GetDataFileContent()) > 0 || cfg.record != nil {
+		findings = append(findings, cfg.validateData()...)
+	}
+	return findings
+}
+
+// Checks the data file against the configuration
+func (cfg *CFG) validateData() (findings Findings) {
+	add := func(severity Severity, rule, format string, args ...interface{}) {
+		findings = append(findings, Finding{severity, rule, fmt.Sprintf(format, args...)})
+	}
+
+	// Data file length
+	content, num := cfg.GetDataFileContent(), cfg.GetSamplingNumber()
+	if len(content) > 0 {
+		switch cfg.dataFileType() {
+		case DataFileASCII:
+			lines := 0
+			for _, line := range bytes.Split(content, []byte("\n")) {
+				if line = bytes.TrimSpace(line); len(line) > 0 && !(len(line) == 1 && line[0] == 0x1A) {
+					lines++
+				}
+			}
+			if lines != num {
+				add(SeverityError, "dat-length", "%d records in ASCII data file, %d expected", lines, num)
+			}
+		case DataFileBinary, DataFileBinary32, DataFileFloat32:
+			if NB := cfg.recordSize(); len(content) != num*NB {
+				add(SeverityError, "dat-length", "%d bytes in binary data file, %d expected (%d records of %d bytes)", len(content), num*NB, num, NB)
+			}
+		}
+	}
+
+	record, err := cfg.GetRecord()
+	if err != nil {
+		add(SeverityError, "dat", "%v", err)
+		return findings
+	}
+
+	// Sample numbers and time stamps, reported once with the first offending record
+	var prev uint32
+	hasPrev := false
+	unordered, first := 0, 0
+	disordered, back := 0, 0
+	missing := 0
+	for i, v := range record.GetStamps() {
+		if record.Samples[i] != uint32(i+1) {
+			if unordered++; unordered == 1 {
+				first = i
+			}
+		}
+		if v == missingStamp {
+			missing++
+			continue
+		}
+		if hasPrev && v < prev {
+			if disordered++; disordered == 1 {
+				back = i
+			}
+		}
+		prev, hasPrev = v, true
+	}
+	if unordered > 0 {
+		add(SeverityWarning, "sample-number", "%d records not numbered sequentially, first is record %d with sample number %d", unordered, first+1, record.Samples[first])
+	}
+	if disordered > 0 {
+		add(SeverityError, "timestamp", "%d time stamps before the previous one, first is record %d", disordered, back+1)
+	}
+	if missing > 0 {
+		severity := SeverityInfo
+		if cfg.GetSampleRateNum() == 0 {
+			// Time stamps are the only time reference without sampling rate
+			severity = SeverityError
+		}
+		add(severity, "timestamp", "%d records without time stamp", missing)
+	}
+	return findings
+}
@@ -0,0 +1,268 @@
+package comgo
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Number of samples read at once by ReadSamples
+const windowChunk = 4096
+
+/*
+ * Window - Samples of the selected channels read by ReadSamples or ReadTimeRange
+ * @Start: Index of the first sample in the data file, starting at 0
+ * @Samples: Sample number of each sample
+ * @Stamps: Time stamp of each sample (0xFFFFFFFF if missing)
+ * @Times: Date and time of each sample
+ * @AnalogChannels: Numbers of the selected analog channels
+ * @DigitChannels: Numbers of the selected digital channels
+ * @Analog: Values of each selected analog channel converted with factors a and b (NaN if missing)
+ * @Digit: States of each selected digital channel
+ */
+type Window struct {
+	Start          int
+	Samples        []uint32
+	Stamps         []uint32
+	Times          []time.Time
+	AnalogChannels []uint16
+	DigitChannels  []uint16
+	Analog         [][]float64
+	Digit          [][]uint8
+}
+
+func (m *Window) GetStart() int {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *Window) GetSamples() []uint32 {
+	if m != nil {
+		return m.Samples
+	}
+	return nil
+}
+
+func (m *Window) GetStamps() []uint32 {
+	if m != nil {
+		return m.Stamps
+	}
+	return nil
+}
+
+func (m *Window) GetTimes() []time.Time {
+	if m != nil {
+		return m.Times
+	}
+	return nil
+}
+
+func (m *Window) GetAnalogChannels() []uint16 {
+	if m != nil {
+		return m.AnalogChannels
+	}
+	return nil
+}
+
+func (m *Window) GetDigitChannels() []uint16 {
+	if m != nil {
+		return m.DigitChannels
+	}
+	return nil
+}
+
+func (m *Window) GetAnalog() [][]float64 {
+	if m != nil {
+		return m.Analog
+	}
+	return nil
+}
+
+func (m *Window) GetDigit() [][]uint8 {
+	if m != nil {
+		return m.Digit
+	}
+	return nil
+}
+
+// Return the number of samples of the window
+func (m *Window) Len() int {
+	return len(m.GetSamples())
+}
+
+// Check random access to the binary data file is possible
+func (cfg *CFG) checkWindow() error {
+	if cfg == nil {
+		return errors.New("invalid cfg file, read .cfg first")
+	}
+	if cfg.GetAnalogDetail() == nil {
+		return errors.New("invalid analog channel")
+	}
+	if cfg.GetDigitDetail() == nil {
+		return errors.New("invalid digital channel")
+	}
+	if len(cfg.GetSampleDetail()) == 0 || cfg.GetSamplingNumber() < 0 {
+		return errors.New("invalid or not enough sample detail")
+	}
+	switch cfg.dataFileType() {
+	case DataFileBinary, DataFileBinary32, DataFileFloat32:
+		return nil
+	default:
+		return errors.New("random access requires a binary data file, not " + cfg.GetDataFileType())
+	}
+}
+
+// Reads samples start to end-1 (indexes starting at 0) of the binary data file ra
+// Only the records of the window are read, only the selected analog and digital channels are decoded
+func (cfg *CFG) ReadSamples(ra io.ReaderAt, start, end int, analog, digital []uint16) (*Window, error) {
+	if err := cfg.checkWindow(); err != nil {
+		return nil, err
+	}
+	if start < 0 || end < start || end > cfg.GetSamplingNumber() {
+		return nil, errors.New("invalid sample range " + strconv.Itoa(start) + " to " + strconv.Itoa(end))
+	}
+
+	analogDetail := cfg.GetAnalogDetail()
+	factor := analogDetail.GetConversionFactors()
+	for _, num := range analog {
+		if num < 1 || num > analogDetail.GetChannelTotal() {
+			return nil, errors.New("invalid analog channel number " + strconv.Itoa(int(num)))
+		}
+		if len(factor["a"]) < int(num) || len(factor["b"]) < int(num) {
+			return nil, errors.New("invalid conversion factors")
+		}
+	}
+	for _, num := range digital {
+		if num < 1 || num > cfg.GetDigitDetail().GetChannelTotal() {
+			return nil, errors.New("invalid digital channel number " + strconv.Itoa(int(num)))
+		}
+	}
+
+	n := end - start
+	w := Window{
+		Start:          start,
+		Samples:        make([]uint32, n),
+		Stamps:         make([]uint32, n),
+		Times:          make([]time.Time, n),
+		AnalogChannels: append([]uint16(nil), analog...),
+		DigitChannels:  append([]uint16(nil), digital...),
+		Analog:         make([][]float64, len(analog)),
+		Digit:          make([][]uint8, len(digital)),
+	}
+	for k := range w.Analog {
+		w.Analog[k] = make([]float64, n)
+	}
+	for k := range w.Digit {
+		w.Digit[k] = make([]uint8, n)
+	}
+
+	NB := cfg.recordSize()
+	format, size := cfg.dataFileType(), cfg.analogSize()
+	digitOffset := 8 + int(analogDetail.GetChannelTotal())*size
+	startTime := cfg.GetStartTime()
+	buf := make([]byte, NB*minInt(n, windowChunk))
+	for i := 0; i < n; i += windowChunk {
+		count := minInt(n-i, windowChunk)
+		offset := int64(start+i) * int64(NB)
+		if read, err := ra.ReadAt(buf[:count*NB], offset); read < count*NB {
+			if err == nil || err == io.EOF {
+				j := read / NB
+				return nil, datError(start+i+j, int(offset)+j*NB, -1, "", nil, ErrTruncated)
+			}
+			return nil, err
+		}
+		for j := 0; j < count; j++ {
+			s := buf[j*NB : j*NB+NB]
+			w.Samples[i+j] = binary.LittleEndian.Uint32(s[0:4])
+			w.Stamps[i+j] = binary.LittleEndian.Uint32(s[4:8])
+			w.Times[i+j] = startTime.Add(cfg.stampOffset(start+i+j, w.Stamps[i+j]))
+			for k, num := range analog {
+				w.Analog[k][i+j] = binaryAnalog(s, int(num-1), format, size)*factor["a"][num-1] + factor["b"][num-1]
+			}
+			for k, num := range digital {
+				w.Digit[k][i+j] = binaryDigit(s[digitOffset:], int(num-1))
+			}
+		}
+	}
+
+	return &w, nil
+}
+
+// Reads the samples from time from to time to (both included) of the binary data file ra
+// Sample times are computed like GetTimeOffsets, sample indexes are found by a binary search,
+// time stamps being read from ra only if they are used, the times must increase with the samples
+// (set TimeSource to TimeSourceRate for time stamps which wrap around)
+func (cfg *CFG) ReadTimeRange(ra io.ReaderAt, from, to time.Time, analog, digital []uint16) (*Window, error) {
+	if err := cfg.checkWindow(); err != nil {
+		return nil, err
+	}
+	if to.Before(from) {
+		return nil, errors.New("invalid time range, end before start")
+	}
+
+	num := cfg.GetSamplingNumber()
+	offset, err := cfg.sampleOffsetFunc(ra)
+	if err != nil {
+		return nil, err
+	}
+	lower, upper := from.Sub(cfg.GetStartTime()), to.Sub(cfg.GetStartTime())
+	var searchErr error
+	start := sort.Search(num, func(i int) bool {
+		d, err := offset(i)
+		if err != nil && searchErr == nil {
+			searchErr = err
+		}
+		return d >= lower
+	})
+	end := sort.Search(num, func(i int) bool {
+		d, err := offset(i)
+		if err != nil && searchErr == nil {
+			searchErr = err
+		}
+		return d > upper
+	})
+	if searchErr != nil {
+		return nil, searchErr
+	}
+	if end < start {
+		end = start
+	}
+	return cfg.ReadSamples(ra, start, end, analog, digital)
+}
+
+// Return a function giving the time offset of the i-th sample, increasing with i
+// the same as GetTimeOffsets, reading the time stamp from ra only if it is used (see TimeSource)
+func (cfg *CFG) sampleOffsetFunc(ra io.ReaderAt) (func(i int) (time.Duration, error), error) {
+	for _, v := range cfg.GetSampleDetail() {
+		if (cfg.GetSampleRateNum() == 0 || v.GetRate() <= 0) && cfg.GetTimeFactor() <= 0 {
+			return nil, errors.New("no sampling rate nor time multiplication factor to locate samples by time")
+		}
+	}
+	NB := cfg.recordSize()
+	stamp := make([]byte, 4)
+	return func(i int) (time.Duration, error) {
+		if !cfg.usesStamp(i) {
+			return cfg.stampOffset(i, missingStamp), nil
+		}
+		offset := int64(i)*int64(NB) + 4
+		if read, err := ra.ReadAt(stamp, offset); read < len(stamp) {
+			if err == nil || err == io.EOF {
+				err = datError(i, int(offset-4), 1, "time stamp", nil, ErrTruncated)
+			}
+			return 0, err
+		}
+		return cfg.stampOffset(i, binary.LittleEndian.Uint32(stamp)), nil
+	}, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
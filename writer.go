@@ -0,0 +1,443 @@
+package comgo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Date and time layouts of the .cfg file
+const (
+	cfgTimeLayout     = "02/01/2006,15:04:05.000000"
+	cfgTimeLayoutNano = "02/01/2006,15:04:05.000000000"
+	cfgTimeLayout1991 = "01/02/06,15:04:05.000000"
+)
+
+// Largest magnitude of the raw analog values of each integer data file type
+// the smallest negative value is reserved as missing value marker
+var rawLimits = map[string]float64{
+	DataFileASCII:    99999,
+	DataFileBinary:   math.MaxInt16,
+	DataFileBinary32: math.MaxInt32,
+}
+
+// Writes the configuration file (.cfg) of the record
+// The layout follows the revision rules of RevisionYear (1991, 1999 or 2013)
+func (cfg *CFG) WriteCFG(w io.Writer) error {
+	if cfg == nil {
+		return errors.New("invalid cfg file, read .cfg first")
+	}
+
+	analogDetail, digitDetail := cfg.GetAnalogDetail(), cfg.GetDigitDetail()
+	if analogDetail == nil || digitDetail == nil {
+		return errors.New("invalid analog or digital channel")
+	}
+
+	revision, rules := cfg.GetRevisionYear(), cfg.GetRevisionRules()
+	bw := bufio.NewWriter(w)
+	line := func(fields ...string) {
+		for k, v := range fields {
+			if k > 0 {
+				bw.WriteByte(',')
+			}
+			bw.WriteString(v)
+		}
+		bw.WriteString("\r\n")
+	}
+
+	// Station name, recording device id and revision year
+	if rules == Revision1991 {
+		line(cfg.GetStationName(), cfg.GetRecordDeviceId())
+	} else {
+		line(cfg.GetStationName(), cfg.GetRecordDeviceId(), strconv.Itoa(int(revision)))
+	}
+
+	// Number and type of channels
+	nA, nD := int(analogDetail.GetChannelTotal()), int(digitDetail.GetChannelTotal())
+	line(strconv.Itoa(nA+nD), strconv.Itoa(nA)+"A", strconv.Itoa(nD)+"D")
+
+	// Analog channels
+	factor := analogDetail.GetConversionFactors()
+	for i := 0; i < nA; i++ {
+		fields := []string{
+			strconv.Itoa(int(channelNumber(analogDetail.GetChannelNumber(), i))),
+			stringAt(analogDetail.GetChannelNames(), i),
+			stringAt(analogDetail.GetChannelPhases(), i),
+			stringAt(analogDetail.GetChannelElements(), i),
+			stringAt(analogDetail.GetChannelUnits(), i),
+			formatFloat(floatAt(factor["a"], i, 1)),
+			formatFloat(floatAt(factor["b"], i, 0)),
+			formatFloat(floatAt(analogDetail.GetTimeFactors(), i, 0)),
+			formatLimit(analogDetail.GetValueMinReal(), analogDetail.GetValueMin(), i),
+			formatLimit(analogDetail.GetValueMaxReal(), analogDetail.GetValueMax(), i),
+		}
+		if rules != Revision1991 {
+			fields = append(fields,
+				formatFloat(floatAt(analogDetail.GetPrimary(), i, 1)),
+				formatFloat(floatAt(analogDetail.GetSecondary(), i, 1)),
+				psAt(analogDetail.GetPS(), i),
+			)
+		}
+		line(fields...)
+	}
+
+	// Digital channels
+	for i := 0; i < nD; i++ {
+		state := strconv.Itoa(int(uint8At(digitDetail.GetInitialState(), i)))
+		if rules == Revision1991 {
+			line(strconv.Itoa(int(channelNumber(digitDetail.GetChannelNumber(), i))), stringAt(digitDetail.GetChannelNames(), i), state)
+			continue
+		}
+		line(
+			strconv.Itoa(int(channelNumber(digitDetail.GetChannelNumber(), i))),
+			stringAt(digitDetail.GetChannelNames(), i),
+			stringAt(digitDetail.GetChannelPhases(), i),
+			stringAt(digitDetail.GetChannelElements(), i),
+			state,
+		)
+	}
+
+	// Line frequency and sampling rates
+	line(strconv.Itoa(int(cfg.GetLineFrequency())))
+	line(strconv.Itoa(int(cfg.GetSampleRateNum())))
+	sampleDetail := cfg.GetSampleDetail()
+	if len(sampleDetail) == 0 {
+		return errors.New("invalid or not enough sample detail")
+	}
+	for _, v := range sampleDetail {
+		line(formatFloat(v.GetRate()), strconv.Itoa(v.GetNumber()))
+	}
+
+	// Start and trigger date and time
+	nano := rules == Revision2013 && cfg.GetTimeBase() == time.Nanosecond
+	line(formatCFGTime(cfg.GetStartTime(), rules, nano))
+	line(formatCFGTime(cfg.GetTriggerTime(), rules, nano))
+
+	// Data file type and time multiplication factor
+	line(cfg.dataFileType())
+	if rules != Revision1991 {
+		line(formatFloat(cfg.GetTimeFactor()))
+	}
+	if rules == Revision2013 {
+		timeCode, localCode := cfg.GetTimeCode(), cfg.GetLocalCode()
+		if timeCode == "" {
+			timeCode = "0"
+		}
+		if localCode == "" {
+			localCode = "x"
+		}
+		line(timeCode, localCode)
+		line(strings.ToUpper(strconv.FormatUint(uint64(cfg.GetTimeQuality()), 16)), strconv.Itoa(int(cfg.GetLeapSecond())))
+	}
+
+	return bw.Flush()
+}
+
+// Writes the data file (.dat) of the record in format
+// (ASCII, BINARY, BINARY32 or FLOAT32)
+// The raw values are written as they are, an error is returned if they do not fit in format,
+// use Rescale to compute conversion factors for format first. cfg is not modified
+func (cfg *CFG) WriteDAT(w io.Writer, format string) error {
+	record, err := cfg.writableRecord()
+	if err != nil {
+		return err
+	}
+	format, err = writableFormat(format)
+	if err != nil {
+		return err
+	}
+
+	if limit, ok := rawLimits[format]; ok {
+		for k, column := range record.GetAnalog() {
+			if !fitsRaw(column, limit) {
+				return errors.New("raw values of analog channel " + strconv.Itoa(k+1) + " do not fit in " + format + ", see Rescale")
+			}
+		}
+	}
+
+	var content []byte
+	if format == DataFileASCII {
+		content = encodeASCII(record)
+	} else {
+		content = encodeBinary(record, format)
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+// Sets the decoded content of the data file, for example a synthesized waveform
+// Analog values are raw values: y = factorA * x + factorB
+func (cfg *CFG) SetRecord(record *Record) {
+	if cfg != nil {
+		cfg.setRecord(record)
+	}
+}
+
+// Return a copy of the record to be written in format (ASCII, BINARY, BINARY32 or FLOAT32)
+// The conversion factors and min/max values of the analog channels whose raw values do not
+// fit in format are computed from the sample values, the others are kept.
+// cfg is not modified, the copy is written with WriteCFG and WriteDAT
+func (cfg *CFG) Rescale(format string) (*CFG, error) {
+	record, err := cfg.writableRecord()
+	if err != nil {
+		return nil, err
+	}
+	format, err = writableFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	analogDetail := cfg.GetAnalogDetail()
+	if analogDetail == nil {
+		return nil, errors.New("invalid analog channel")
+	}
+
+	// Copy what is changed, the data file content and the mapping stay with cfg
+	out := *cfg
+	out.DataFileType = format
+	out.DataFileContent = nil
+	out.mapped = nil
+	detail := *analogDetail
+	factor := analogDetail.GetConversionFactors()
+	detail.ConversionFactors = map[string][]float64{
+		"a": append([]float64(nil), factor["a"]...),
+		"b": append([]float64(nil), factor["b"]...),
+	}
+	detail.ValueMin = append([]int(nil), analogDetail.GetValueMin()...)
+	detail.ValueMax = append([]int(nil), analogDetail.GetValueMax()...)
+	detail.ValueMinReal = append([]float64(nil), analogDetail.GetValueMinReal()...)
+	detail.ValueMaxReal = append([]float64(nil), analogDetail.GetValueMaxReal()...)
+	out.AnalogDetail = &detail
+	rescaled := *record
+	rescaled.Analog = append([][]float64(nil), record.GetAnalog()...)
+
+	limit, ok := rawLimits[format]
+	for k, column := range record.GetAnalog() {
+		if !ok || fitsRaw(column, limit) {
+			continue
+		}
+
+		// Scale the sample values to [-limit, limit]
+		a, b := floatAt(factor["a"], k, 1), floatAt(factor["b"], k, 0)
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, v := range column {
+			if !math.IsNaN(v) {
+				min, max = math.Min(min, v*a+b), math.Max(max, v*a+b)
+			}
+		}
+		newA, newB := 1.0, 0.0
+		if min <= max {
+			newB = (max + min) / 2
+			if max > min {
+				newA = (max - min) / (2 * limit)
+			}
+		}
+		scaled := make([]float64, len(column))
+		for i, v := range column {
+			scaled[i] = v
+			if !math.IsNaN(v) {
+				scaled[i] = math.Round((v*a + b - newB) / newA)
+			}
+		}
+		rescaled.Analog[k] = scaled
+
+		setFloatAt(detail.ConversionFactors, "a", k, 1, newA)
+		setFloatAt(detail.ConversionFactors, "b", k, 0, newB)
+		setLimitAt(&detail.ValueMin, &detail.ValueMinReal, k, -int(limit))
+		setLimitAt(&detail.ValueMax, &detail.ValueMaxReal, k, int(limit))
+	}
+	out.setRecord(&rescaled)
+	return &out, nil
+}
+
+// Return the record to be written, with the number of samples of the configuration
+func (cfg *CFG) writableRecord() (*Record, error) {
+	record, err := cfg.GetRecord()
+	if err != nil {
+		return nil, err
+	}
+	if record.Len() != cfg.GetSamplingNumber() {
+		return nil, errors.New("number of samples does not match sample detail")
+	}
+	return record, nil
+}
+
+// Set the k-th integer and exact min or max values to v
+// missing exact values before k are the integer values
+func setLimitAt(values *[]int, reals *[]float64, k int, v int) {
+	for len(*values) <= k {
+		*values = append(*values, 0)
+	}
+	if len(*reals) > 0 {
+		for len(*reals) <= k {
+			*reals = append(*reals, float64((*values)[len(*reals)]))
+		}
+		(*reals)[k] = float64(v)
+	}
+	(*values)[k] = v
+}
+
+// Return format normalized, if it is a data file type that can be written
+func writableFormat(format string) (string, error) {
+	format = normalizeDataFileType(format)
+	switch format {
+	case DataFileASCII, DataFileBinary, DataFileBinary32, DataFileFloat32:
+		return format, nil
+	default:
+		return "", errors.New("unsupported data file type: " + format)
+	}
+}
+
+// Set the k-th factor key to v, missing factors before k are def
+func setFloatAt(factor map[string][]float64, key string, k int, def, v float64) {
+	for len(factor[key]) <= k {
+		factor[key] = append(factor[key], def)
+	}
+	factor[key][k] = v
+}
+
+// Encodes record as ASCII data file content
+func encodeASCII(record *Record) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < record.Len(); i++ {
+		buf.WriteString(strconv.FormatUint(uint64(record.Samples[i]), 10))
+		buf.WriteByte(',')
+		if record.Stamps[i] != missingStamp {
+			buf.WriteString(strconv.FormatUint(uint64(record.Stamps[i]), 10))
+		}
+		for _, column := range record.Analog {
+			buf.WriteByte(',')
+			if !math.IsNaN(column[i]) {
+				buf.WriteString(strconv.FormatInt(int64(column[i]), 10))
+			}
+		}
+		for _, states := range record.Digit {
+			buf.WriteByte(',')
+			buf.WriteByte('0' + states.Get(i))
+		}
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes()
+}
+
+// Encodes record as binary data file content of type format
+func encodeBinary(record *Record, format string) []byte {
+	size := analogSizeOf(format)
+	NB := 8 + len(record.Analog)*size + (len(record.Digit)+15)>>4<<1
+	content := make([]byte, record.Len()*NB)
+	for i := 0; i < record.Len(); i++ {
+		s := content[i*NB : i*NB+NB]
+		binary.LittleEndian.PutUint32(s[0:4], record.Samples[i])
+		binary.LittleEndian.PutUint32(s[4:8], record.Stamps[i])
+		s = s[8:]
+		for k, column := range record.Analog {
+			v := column[i]
+			switch format {
+			case DataFileBinary32:
+				if math.IsNaN(v) {
+					binary.LittleEndian.PutUint32(s[k*size:], missingBinary32)
+				} else {
+					binary.LittleEndian.PutUint32(s[k*size:], uint32(int32(v)))
+				}
+			case DataFileFloat32:
+				binary.LittleEndian.PutUint32(s[k*size:], math.Float32bits(float32(v)))
+			default:
+				if math.IsNaN(v) {
+					binary.LittleEndian.PutUint16(s[k*size:], missingBinary)
+				} else {
+					binary.LittleEndian.PutUint16(s[k*size:], uint16(int16(v)))
+				}
+			}
+		}
+		s = s[len(record.Analog)*size:]
+		for k, states := range record.Digit {
+			s[(k>>4)<<1+(k&15)>>3] |= states.Get(i) << uint(k&7)
+		}
+	}
+	return content
+}
+
+// Check if every value of column is an integer in [-limit, limit]
+func fitsRaw(column []float64, limit float64) bool {
+	for _, v := range column {
+		if math.IsNaN(v) {
+			continue
+		}
+		if v != math.Trunc(v) || v < -limit || v > limit {
+			return false
+		}
+	}
+	return true
+}
+
+// Format date and time as in .cfg file of the revision
+// nano selects nanosecond resolution of the 2013 revision
+func formatCFGTime(t time.Time, rules uint16, nano bool) string {
+	switch {
+	case rules == Revision1991:
+		return t.Format(cfgTimeLayout1991)
+	case nano:
+		return t.Format(cfgTimeLayoutNano)
+	default:
+		return t.Format(cfgTimeLayout)
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func channelNumber(list []uint16, i int) uint16 {
+	if i < len(list) {
+		return list[i]
+	}
+	return uint16(i + 1)
+}
+
+func stringAt(list []string, i int) string {
+	if i < len(list) {
+		return list[i]
+	}
+	return ""
+}
+
+func floatAt(list []float64, i int, def float64) float64 {
+	if i < len(list) {
+		return list[i]
+	}
+	return def
+}
+
+func psAt(list []string, i int) string {
+	if i < len(list) && list[i] == ValueSecondary {
+		return ValueSecondary
+	}
+	return ValuePrimary
+}
+
+// Format the exact min or max value of the i-th channel, or its integer value if not set
+func formatLimit(reals []float64, values []int, i int) string {
+	if i < len(reals) {
+		return formatFloat(reals[i])
+	}
+	return strconv.Itoa(intAt(values, i))
+}
+
+func intAt(list []int, i int) int {
+	if i < len(list) {
+		return list[i]
+	}
+	return 0
+}
+
+func uint8At(list []uint8, i int) uint8 {
+	if i < len(list) {
+		return list[i]
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+package comgo
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// Data file types written by WriteDAT
+var testFormats = []string{DataFileASCII, DataFileBinary, DataFileBinary32, DataFileFloat32}
+
+// Writes cfg in format with Rescale, WriteCFG and WriteDAT and reads the files back
+func roundTrip(t *testing.T, cfg *CFG, format string) *CFG {
+	t.Helper()
+	out, err := cfg.Rescale(format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfgBuf, datBuf bytes.Buffer
+	if err := out.WriteCFG(&cfgBuf); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.WriteDAT(&datBuf, format); err != nil {
+		t.Fatal(err)
+	}
+
+	read := New()
+	if err := read.ReadCFG(&cfgBuf); err != nil {
+		t.Fatal(err)
+	}
+	if err := read.ReadDAT(&datBuf); err != nil {
+		t.Fatal(err)
+	}
+	return &read
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	for _, name := range testRecords {
+		cfg := readTestRecord(t, name)
+		for _, format := range testFormats {
+			read := roundTrip(t, cfg, format)
+			if read.GetDataFileType() != format {
+				t.Errorf("%s %s: data file type %s", name, format, read.GetDataFileType())
+			}
+			if read.GetSamplingNumber() != cfg.GetSamplingNumber() {
+				t.Fatalf("%s %s: %d samples, want %d", name, format, read.GetSamplingNumber(), cfg.GetSamplingNumber())
+			}
+
+			for num := uint16(1); num <= cfg.GetAnalogDetail().GetChannelTotal(); num++ {
+				want, err := cfg.GetAnalogChannelData(num)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := read.GetAnalogChannelData(num)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Conversion factors are written with full precision, FLOAT32 values are rounded
+				for i := range want {
+					if math.IsNaN(want[i]) != math.IsNaN(got[i]) || math.Abs(got[i]-want[i]) > 1e-6*math.Max(1, math.Abs(want[i])) {
+						t.Fatalf("%s %s analog channel %d sample %d: %g, want %g", name, format, num, i, got[i], want[i])
+					}
+				}
+			}
+
+			for num := uint16(1); num <= cfg.GetDigitDetail().GetChannelTotal(); num++ {
+				want, err := cfg.GetDigitalChannelData(num)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := read.GetDigitalChannelData(num)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("%s %s digital channel %d differs", name, format, num)
+				}
+			}
+
+			if !read.GetStartTime().Equal(cfg.GetStartTime()) || !read.GetTriggerTime().Equal(cfg.GetTriggerTime()) {
+				t.Errorf("%s %s: start or trigger time differs", name, format)
+			}
+		}
+	}
+}
+
+func TestWriteBinaryIdentical(t *testing.T) {
+	for _, name := range testRecords {
+		cfg := readTestRecord(t, name)
+		var buf bytes.Buffer
+		if err := cfg.WriteDAT(&buf, DataFileBinary); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), readTestDAT(t, name)) {
+			t.Errorf("%s: BINARY data file differs from %s.dat", name, name)
+		}
+	}
+}
+
+func TestWriteDATKeepsCFG(t *testing.T) {
+	cfg := readTestRecord(t, "test1")
+	factor := append([]float64(nil), cfg.GetAnalogDetail().GetConversionFactors()["a"]...)
+	var first, second bytes.Buffer
+	if err := cfg.WriteDAT(&first, DataFileBinary32); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.WriteDAT(&bytes.Buffer{}, DataFileASCII); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.WriteDAT(&second, DataFileBinary32); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("writing twice in the same format gives different data files")
+	}
+	if cfg.GetDataFileType() != DataFileBinary || !bytes.Equal(cfg.GetDataFileContent(), readTestDAT(t, "test1")) {
+		t.Error("WriteDAT changed the data file type or content")
+	}
+	for k, v := range cfg.GetAnalogDetail().GetConversionFactors()["a"] {
+		if v != factor[k] {
+			t.Fatalf("WriteDAT changed factor a of analog channel %d", k+1)
+		}
+	}
+}